@@ -0,0 +1,160 @@
+package Netpbm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channel identifies one of the color channels of a PPM image.
+type Channel int
+
+const (
+	ChannelR Channel = iota
+	ChannelG
+	ChannelB
+)
+
+// get returns the value of the channel in a pixel.
+func (c Channel) get(p Pixel) uint16 {
+	switch c {
+	case ChannelG:
+		return p.G
+	case ChannelB:
+		return p.B
+	}
+	return p.R
+}
+
+// set changes the value of the channel in a pixel.
+func (c Channel) set(p *Pixel, value uint16) {
+	switch c {
+	case ChannelG:
+		p.G = value
+	case ChannelB:
+		p.B = value
+	default:
+		p.R = value
+	}
+}
+
+// Channel extracts one color channel of the image as a PGM image.
+func (ppm *PPM) Channel(c Channel) *PGM {
+	data := make([][]uint16, ppm.height)
+	for y := range data {
+		data[y] = make([]uint16, ppm.width)
+		for x := range data[y] {
+			data[y][x] = c.get(ppm.data[y][x])
+		}
+	}
+	return &PGM{data: data, width: ppm.width, height: ppm.height, magicNumber: grayMagicNumber(ppm.magicNumber), max: ppm.max}
+}
+
+// Channels splits the image into three PGM images holding the red, green and
+// blue channels.
+func (ppm *PPM) Channels() (*PGM, *PGM, *PGM) {
+	return ppm.Channel(ChannelR), ppm.Channel(ChannelG), ppm.Channel(ChannelB)
+}
+
+// MergeChannels builds a PPM image from three PGM images used as the red, green
+// and blue channels. The images must have the same size and maxval.
+func MergeChannels(r, g, b *PGM) (*PPM, error) {
+	if r.width != g.width || r.width != b.width || r.height != g.height || r.height != b.height {
+		return nil, fmt.Errorf("channel sizes do not match")
+	}
+	if r.max != g.max || r.max != b.max {
+		return nil, fmt.Errorf("channel max values do not match")
+	}
+	data := make([][]Pixel, r.height)
+	for y := range data {
+		data[y] = make([]Pixel, r.width)
+		for x := range data[y] {
+			data[y][x] = Pixel{R: r.data[y][x], G: g.data[y][x], B: b.data[y][x]}
+		}
+	}
+	return &PPM{data: data, width: r.width, height: r.height, magicNumber: colorMagicNumber(r.magicNumber), max: r.max}, nil
+}
+
+// SetChannel replaces one color channel of the image with a PGM image of the
+// same size and maxval.
+func (ppm *PPM) SetChannel(c Channel, plane *PGM) error {
+	if plane.width != ppm.width || plane.height != ppm.height {
+		return fmt.Errorf("channel size does not match the image")
+	}
+	if plane.max != ppm.max {
+		return fmt.Errorf("channel max value %d does not match the image (%d)", plane.max, ppm.max)
+	}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			c.set(&ppm.data[y][x], plane.data[y][x])
+		}
+	}
+	return nil
+}
+
+// FillChannel sets one color channel of every pixel to a constant value.
+func (ppm *PPM) FillChannel(c Channel, value uint16) {
+	if int(value) > ppm.max {
+		value = uint16(ppm.max)
+	}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			c.set(&ppm.data[y][x], value)
+		}
+	}
+}
+
+// Swizzle reorders the color channels. order names, for the red, green and blue
+// channels of the result, the source channel to read: "BGR" swaps red and blue,
+// "GGG" copies green everywhere.
+func (ppm *PPM) Swizzle(order string) error {
+	order = strings.ToUpper(order)
+	if len(order) != 3 {
+		return fmt.Errorf("invalid channel order: %s", order)
+	}
+	var source [3]Channel
+	for i, letter := range order {
+		switch letter {
+		case 'R':
+			source[i] = ChannelR
+		case 'G':
+			source[i] = ChannelG
+		case 'B':
+			source[i] = ChannelB
+		default:
+			return fmt.Errorf("invalid channel order: %s", order)
+		}
+	}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			p := ppm.data[y][x]
+			ppm.data[y][x] = Pixel{R: source[0].get(p), G: source[1].get(p), B: source[2].get(p)}
+		}
+	}
+	return nil
+}
+
+// SwapRB exchanges the red and blue channels (RGB to BGR).
+func (ppm *PPM) SwapRB() {
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			p := &ppm.data[y][x]
+			p.R, p.B = p.B, p.R
+		}
+	}
+}
+
+// mapChannels runs fn on each color channel, given as a plane indexed [y][x],
+// and rebuilds the image from the resulting width x height planes.
+func (ppm *PPM) mapChannels(width, height int, fn func(c Channel, plane [][]uint16) [][]uint16) {
+	out := newGrid[Pixel](width, height)
+	for c := ChannelR; c <= ChannelB; c++ {
+		plane := fn(c, ppm.Channel(c).data)
+		for y := range out {
+			for x := range out[y] {
+				c.set(&out[y][x], plane[y][x])
+			}
+		}
+	}
+	ppm.data = storeGrid(ppm.data, out)
+	ppm.width, ppm.height = width, height
+}
@@ -0,0 +1,86 @@
+package Netpbm
+
+import "testing"
+
+func TestChannelsRoundTrip(t *testing.T) {
+	ppm := newTestPPM(3, 2, func(x, y int) Pixel { return Pixel{uint16(x), uint16(10 + y), uint16(x + y)} })
+	r, g, b := ppm.Channels()
+	if r.At(0, 2) != 2 || g.data[1][0] != 11 || b.data[1][2] != 3 {
+		t.Fatalf("wrong channel values: %v %v %v", r.data, g.data, b.data)
+	}
+	merged, err := MergeChannels(r, g, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range merged.data {
+		for x := range merged.data[y] {
+			if merged.data[y][x] != ppm.data[y][x] {
+				t.Fatalf("pixel (%d, %d): got %v, want %v", x, y, merged.data[y][x], ppm.data[y][x])
+			}
+		}
+	}
+}
+
+func TestSetChannel(t *testing.T) {
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{1, 2, 3} })
+	plane := ppm.Channel(ChannelR)
+	plane.data[1][1] = 200
+	if err := ppm.SetChannel(ChannelB, plane); err != nil {
+		t.Fatalf("plane with a matching max value rejected: %v", err)
+	}
+	if got := ppm.data[1][1]; got != (Pixel{1, 2, 200}) {
+		t.Fatalf("got %v, want {1 2 200}", got)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{1, 2, 1}) {
+		t.Fatalf("got %v, want {1 2 1}", got)
+	}
+}
+
+func TestSetChannelRejectsMismatch(t *testing.T) {
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{1, 2, 3} })
+	plane := ppm.Channel(ChannelG)
+	plane.max = 1000
+	if err := ppm.SetChannel(ChannelG, plane); err == nil {
+		t.Fatal("plane with a different max value accepted")
+	}
+	small := &PGM{data: [][]uint16{{0}}, width: 1, height: 1, magicNumber: "P2", max: 255}
+	if err := ppm.SetChannel(ChannelG, small); err == nil {
+		t.Fatal("plane with a different size accepted")
+	}
+	if got := ppm.data[0][0]; got != (Pixel{1, 2, 3}) {
+		t.Fatalf("rejected plane changed the image: %v", got)
+	}
+}
+
+func TestSwizzle(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{1, 2, 3} })
+	if err := ppm.Swizzle("bgr"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{3, 2, 1}) {
+		t.Fatalf("BGR: got %v", got)
+	}
+	if err := ppm.Swizzle("GGG"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{2, 2, 2}) {
+		t.Fatalf("GGG: got %v", got)
+	}
+	if err := ppm.Swizzle("RGX"); err == nil {
+		t.Fatal("invalid order accepted")
+	}
+	ppm.data[0][0] = Pixel{1, 2, 3}
+	ppm.SwapRB()
+	if got := ppm.data[0][0]; got != (Pixel{3, 2, 1}) {
+		t.Fatalf("SwapRB: got %v", got)
+	}
+}
+
+func TestFillChannelClampsToMax(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{} })
+	ppm.max = 100
+	ppm.FillChannel(ChannelG, 200)
+	if got := ppm.data[0][0].G; got != 100 {
+		t.Fatalf("got %d, want 100", got)
+	}
+}
@@ -0,0 +1,498 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// HSV is a color in the hue/saturation/value model. H is in degrees [0, 360),
+// S and V are in [0, 1].
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL is a color in the hue/saturation/lightness model. H is in degrees
+// [0, 360), S and L are in [0, 1].
+type HSL struct {
+	H, S, L float64
+}
+
+// YCbCr is a full-range luma/chroma color. Y is in [0, 1], Cb and Cr are in
+// [-0.5, 0.5].
+type YCbCr struct {
+	Y, Cb, Cr float64
+}
+
+// XYZ is a CIE 1931 XYZ color relative to the D65 white point, with Y = 1 for
+// white.
+type XYZ struct {
+	X, Y, Z float64
+}
+
+// Lab is a CIE L*a*b* color relative to the D65 white point. L is in [0, 100].
+type Lab struct {
+	L, A, B float64
+}
+
+// LCh is the cylindrical form of Lab. H is in degrees [0, 360).
+type LCh struct {
+	L, C, H float64
+}
+
+// YCbCrStandard selects the luma coefficients of a YCbCr conversion.
+type YCbCrStandard int
+
+const (
+	// BT601 uses the ITU-R BT.601 (standard definition) coefficients.
+	BT601 YCbCrStandard = iota
+	// BT709 uses the ITU-R BT.709 (high definition) coefficients.
+	BT709
+)
+
+// ColorSpace identifies a color space for whole-image conversions.
+type ColorSpace int
+
+const (
+	SpaceRGB ColorSpace = iota
+	SpaceHSV
+	SpaceHSL
+	SpaceYCbCr601
+	SpaceYCbCr709
+	SpaceXYZ
+	SpaceLab
+	SpaceLCh
+)
+
+// D65 reference white.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// srgbToLinear removes the sRGB transfer curve from a component in [0, 1].
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB applies the sRGB transfer curve to a linear component in [0, 1].
+func linearToSRGB(c float64) float64 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*math.Pow(c, 1/2.4) - 0.055
+}
+
+// normalize returns the components of the pixel scaled to [0, 1].
+func (p Pixel) normalize(max int) (float64, float64, float64) {
+	if max <= 0 {
+		return 0, 0, 0
+	}
+	m := float64(max)
+	return float64(p.R) / m, float64(p.G) / m, float64(p.B) / m
+}
+
+// pixelFromUnit builds a pixel from components in [0, 1], rounding to the
+// nearest sample value.
+func pixelFromUnit(r, g, b float64, max int) Pixel {
+	return Pixel{R: unitToSample(r, max), G: unitToSample(g, max), B: unitToSample(b, max)}
+}
+
+func unitToSample(v float64, max int) uint16 {
+	if v != v || v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return uint16(max)
+	}
+	return uint16(math.Round(v * float64(max)))
+}
+
+func normalizeHue(h float64) float64 {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// hueChroma returns the hue in degrees of an RGB triplet along with its
+// largest and smallest components.
+func hueChroma(r, g, b float64) (h, max, min float64) {
+	max = math.Max(r, math.Max(g, b))
+	min = math.Min(r, math.Min(g, b))
+	d := max - min
+	if d == 0 {
+		return 0, max, min
+	}
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return normalizeHue(h * 60), max, min
+}
+
+// rgbFromHue builds an RGB triplet from a hue, a chroma and the offset added
+// to every component.
+func rgbFromHue(h, c, m float64) (float64, float64, float64) {
+	h = normalizeHue(h) / 60
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch {
+	case h < 1:
+		r, g, b = c, x, 0
+	case h < 2:
+		r, g, b = x, c, 0
+	case h < 3:
+		r, g, b = 0, c, x
+	case h < 4:
+		r, g, b = 0, x, c
+	case h < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// ToHSV converts the pixel to HSV. max is the maxval of the image.
+func (p Pixel) ToHSV(max int) HSV {
+	h, hi, lo := hueChroma(p.normalize(max))
+	s := 0.0
+	if hi > 0 {
+		s = (hi - lo) / hi
+	}
+	return HSV{H: h, S: s, V: hi}
+}
+
+// ToPixel converts the HSV color to a pixel with the given maxval.
+func (c HSV) ToPixel(max int) Pixel {
+	chroma := c.V * c.S
+	r, g, b := rgbFromHue(c.H, chroma, c.V-chroma)
+	return pixelFromUnit(r, g, b, max)
+}
+
+// ToHSL converts the pixel to HSL. max is the maxval of the image.
+func (p Pixel) ToHSL(max int) HSL {
+	h, hi, lo := hueChroma(p.normalize(max))
+	l := (hi + lo) / 2
+	s := 0.0
+	if d := hi - lo; d > 0 {
+		s = d / (1 - math.Abs(2*l-1))
+	}
+	return HSL{H: h, S: s, L: l}
+}
+
+// ToPixel converts the HSL color to a pixel with the given maxval.
+func (c HSL) ToPixel(max int) Pixel {
+	chroma := (1 - math.Abs(2*c.L-1)) * c.S
+	r, g, b := rgbFromHue(c.H, chroma, c.L-chroma/2)
+	return pixelFromUnit(r, g, b, max)
+}
+
+func lumaCoefficients(std YCbCrStandard) (kr, kb float64) {
+	if std == BT709 {
+		return 0.2126, 0.0722
+	}
+	return 0.299, 0.114
+}
+
+// ToYCbCr converts the pixel to full-range YCbCr using the given standard.
+func (p Pixel) ToYCbCr(max int, std YCbCrStandard) YCbCr {
+	r, g, b := p.normalize(max)
+	kr, kb := lumaCoefficients(std)
+	y := kr*r + (1-kr-kb)*g + kb*b
+	return YCbCr{Y: y, Cb: (b - y) / (2 * (1 - kb)), Cr: (r - y) / (2 * (1 - kr))}
+}
+
+// ToPixel converts the YCbCr color to a pixel with the given maxval.
+func (c YCbCr) ToPixel(max int, std YCbCrStandard) Pixel {
+	kr, kb := lumaCoefficients(std)
+	r := c.Y + 2*(1-kr)*c.Cr
+	b := c.Y + 2*(1-kb)*c.Cb
+	g := (c.Y - kr*r - kb*b) / (1 - kr - kb)
+	return pixelFromUnit(r, g, b, max)
+}
+
+// ToXYZ converts the pixel, taken as sRGB, to CIE XYZ.
+func (p Pixel) ToXYZ(max int) XYZ {
+	r, g, b := p.normalize(max)
+	r, g, b = srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)
+	return XYZ{
+		X: 0.4124564*r + 0.3575761*g + 0.1804375*b,
+		Y: 0.2126729*r + 0.7151522*g + 0.0721750*b,
+		Z: 0.0193339*r + 0.1191920*g + 0.9503041*b,
+	}
+}
+
+// ToPixel converts the XYZ color to an sRGB pixel with the given maxval.
+// Out-of-gamut colors are clipped.
+func (c XYZ) ToPixel(max int) Pixel {
+	r := 3.2404542*c.X - 1.5371385*c.Y - 0.4985314*c.Z
+	g := -0.9692660*c.X + 1.8760108*c.Y + 0.0415560*c.Z
+	b := 0.0556434*c.X - 0.2040259*c.Y + 1.0572252*c.Z
+	return pixelFromUnit(linearToSRGB(clampUnit(r)), linearToSRGB(clampUnit(g)), linearToSRGB(clampUnit(b)), max)
+}
+
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// labF is the companding function of the CIE XYZ to Lab conversion.
+func labF(t float64) float64 {
+	if t > 216.0/24389.0 {
+		return math.Cbrt(t)
+	}
+	return (24389.0/27.0*t + 16) / 116
+}
+
+func labFInverse(t float64) float64 {
+	if t*t*t > 216.0/24389.0 {
+		return t * t * t
+	}
+	return (116*t - 16) * 27.0 / 24389.0
+}
+
+// ToLab converts the XYZ color to CIE Lab.
+func (c XYZ) ToLab() Lab {
+	fx, fy, fz := labF(c.X/whiteX), labF(c.Y/whiteY), labF(c.Z/whiteZ)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// ToXYZ converts the Lab color to CIE XYZ.
+func (c Lab) ToXYZ() XYZ {
+	fy := (c.L + 16) / 116
+	fx := fy + c.A/500
+	fz := fy - c.B/200
+	return XYZ{X: whiteX * labFInverse(fx), Y: whiteY * labFInverse(fy), Z: whiteZ * labFInverse(fz)}
+}
+
+// ToLab converts the pixel, taken as sRGB, to CIE Lab.
+func (p Pixel) ToLab(max int) Lab {
+	return p.ToXYZ(max).ToLab()
+}
+
+// ToPixel converts the Lab color to an sRGB pixel with the given maxval.
+func (c Lab) ToPixel(max int) Pixel {
+	return c.ToXYZ().ToPixel(max)
+}
+
+// ToLCh converts the Lab color to its cylindrical form.
+func (c Lab) ToLCh() LCh {
+	return LCh{L: c.L, C: math.Hypot(c.A, c.B), H: normalizeHue(math.Atan2(c.B, c.A) * 180 / math.Pi)}
+}
+
+// ToLab converts the LCh color back to Lab.
+func (c LCh) ToLab() Lab {
+	h := c.H * math.Pi / 180
+	return Lab{L: c.L, A: c.C * math.Cos(h), B: c.C * math.Sin(h)}
+}
+
+// ToLCh converts the pixel, taken as sRGB, to LCh.
+func (p Pixel) ToLCh(max int) LCh {
+	return p.ToLab(max).ToLCh()
+}
+
+// ToPixel converts the LCh color to an sRGB pixel with the given maxval.
+func (c LCh) ToPixel(max int) Pixel {
+	return c.ToLab().ToPixel(max)
+}
+
+// DeltaE76 returns the CIE 1976 color difference between two Lab colors.
+func DeltaE76(a, b Lab) float64 {
+	return math.Sqrt((a.L-b.L)*(a.L-b.L) + (a.A-b.A)*(a.A-b.A) + (a.B-b.B)*(a.B-b.B))
+}
+
+// DeltaE94 returns the CIE 1994 color difference (graphic arts weights)
+// between a reference color and a sample.
+func DeltaE94(reference, sample Lab) float64 {
+	c1 := math.Hypot(reference.A, reference.B)
+	c2 := math.Hypot(sample.A, sample.B)
+	dl := reference.L - sample.L
+	dc := c1 - c2
+	da := reference.A - sample.A
+	db := reference.B - sample.B
+	dh2 := da*da + db*db - dc*dc
+	if dh2 < 0 {
+		dh2 = 0
+	}
+	sc := 1 + 0.045*c1
+	sh := 1 + 0.015*c1
+	return math.Sqrt(dl*dl + (dc/sc)*(dc/sc) + dh2/(sh*sh))
+}
+
+// toSpace converts a pixel to the components of a color space.
+func (p Pixel) toSpace(space ColorSpace, max int) [3]float64 {
+	switch space {
+	case SpaceHSV:
+		c := p.ToHSV(max)
+		return [3]float64{c.H, c.S, c.V}
+	case SpaceHSL:
+		c := p.ToHSL(max)
+		return [3]float64{c.H, c.S, c.L}
+	case SpaceYCbCr601, SpaceYCbCr709:
+		c := p.ToYCbCr(max, spaceStandard(space))
+		return [3]float64{c.Y, c.Cb, c.Cr}
+	case SpaceXYZ:
+		c := p.ToXYZ(max)
+		return [3]float64{c.X, c.Y, c.Z}
+	case SpaceLab:
+		c := p.ToLab(max)
+		return [3]float64{c.L, c.A, c.B}
+	case SpaceLCh:
+		c := p.ToLCh(max)
+		return [3]float64{c.L, c.C, c.H}
+	}
+	r, g, b := p.normalize(max)
+	return [3]float64{r, g, b}
+}
+
+// pixelFromSpace converts the components of a color space to a pixel.
+func pixelFromSpace(v [3]float64, space ColorSpace, max int) Pixel {
+	switch space {
+	case SpaceHSV:
+		return HSV{v[0], v[1], v[2]}.ToPixel(max)
+	case SpaceHSL:
+		return HSL{v[0], v[1], v[2]}.ToPixel(max)
+	case SpaceYCbCr601, SpaceYCbCr709:
+		return YCbCr{v[0], v[1], v[2]}.ToPixel(max, spaceStandard(space))
+	case SpaceXYZ:
+		return XYZ{v[0], v[1], v[2]}.ToPixel(max)
+	case SpaceLab:
+		return Lab{v[0], v[1], v[2]}.ToPixel(max)
+	case SpaceLCh:
+		return LCh{v[0], v[1], v[2]}.ToPixel(max)
+	}
+	return pixelFromUnit(v[0], v[1], v[2], max)
+}
+
+func spaceStandard(space ColorSpace) YCbCrStandard {
+	if space == SpaceYCbCr709 {
+		return BT709
+	}
+	return BT601
+}
+
+// spaceRanges returns, for each component of a color space, the offset and
+// span used to store it in a PGM plane.
+func spaceRanges(space ColorSpace) (offset, span [3]float64) {
+	switch space {
+	case SpaceHSV, SpaceHSL:
+		return [3]float64{0, 0, 0}, [3]float64{360, 1, 1}
+	case SpaceYCbCr601, SpaceYCbCr709:
+		return [3]float64{0, -0.5, -0.5}, [3]float64{1, 1, 1}
+	case SpaceXYZ:
+		return [3]float64{0, 0, 0}, [3]float64{whiteX, whiteY, whiteZ}
+	case SpaceLab:
+		return [3]float64{0, -128, -128}, [3]float64{100, 255, 255}
+	case SpaceLCh:
+		return [3]float64{0, 0, 0}, [3]float64{100, 150, 360}
+	}
+	return [3]float64{0, 0, 0}, [3]float64{1, 1, 1}
+}
+
+// ToColorSpace converts every pixel of the image to the given color space.
+// The result is indexed [y][x].
+func (ppm *PPM) ToColorSpace(space ColorSpace) [][][3]float64 {
+	out := make([][][3]float64, ppm.height)
+	for y := range out {
+		out[y] = make([][3]float64, ppm.width)
+		for x := range out[y] {
+			out[y][x] = ppm.data[y][x].toSpace(space, ppm.max)
+		}
+	}
+	return out
+}
+
+// FromColorSpace builds a PPM image with the given maxval from components of a
+// color space, indexed [y][x].
+func FromColorSpace(values [][][3]float64, space ColorSpace, max int) *PPM {
+	height := len(values)
+	width := 0
+	if height > 0 {
+		width = len(values[0])
+	}
+	data := make([][]Pixel, height)
+	for y := range data {
+		data[y] = make([]Pixel, width)
+		for x := range data[y] {
+			data[y][x] = pixelFromSpace(values[y][x], space, max)
+		}
+	}
+	return &PPM{data: data, width: width, height: height, magicNumber: "P6", max: max}
+}
+
+// SplitPlanes converts the image to the given color space and stores each
+// component in its own PGM image, scaled to the range [0, maxval].
+func (ppm *PPM) SplitPlanes(space ColorSpace) (*PGM, *PGM, *PGM) {
+	offset, span := spaceRanges(space)
+	max := ppm.max
+	var planes [3]*PGM
+	for c := range planes {
+		planes[c] = &PGM{data: make([][]uint16, ppm.height), width: ppm.width, height: ppm.height,
+			magicNumber: grayMagicNumber(ppm.magicNumber), max: max}
+		for y := range planes[c].data {
+			planes[c].data[y] = make([]uint16, ppm.width)
+		}
+	}
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			v := ppm.data[y][x].toSpace(space, max)
+			for c := range planes {
+				planes[c].data[y][x] = unitToSample((v[c]-offset[c])/span[c], max)
+			}
+		}
+	}
+	return planes[0], planes[1], planes[2]
+}
+
+// MergePlanes is the inverse of SplitPlanes: it reads one component of the
+// given color space from each PGM image and builds a PPM image. The planes
+// must have the same size and maxval.
+func MergePlanes(space ColorSpace, a, b, c *PGM) (*PPM, error) {
+	if a.width != b.width || a.width != c.width || a.height != b.height || a.height != c.height {
+		return nil, fmt.Errorf("plane sizes do not match")
+	}
+	if a.max != b.max || a.max != c.max {
+		return nil, fmt.Errorf("plane max values do not match")
+	}
+	offset, span := spaceRanges(space)
+	planes := [3]*PGM{a, b, c}
+	max := a.max
+	data := make([][]Pixel, a.height)
+	for y := range data {
+		data[y] = make([]Pixel, a.width)
+		for x := range data[y] {
+			var v [3]float64
+			for i, plane := range planes {
+				unit := 0.0
+				if plane.max > 0 {
+					unit = float64(plane.data[y][x]) / float64(plane.max)
+				}
+				v[i] = unit*span[i] + offset[i]
+			}
+			data[y][x] = pixelFromSpace(v, space, max)
+		}
+	}
+	return &PPM{data: data, width: a.width, height: a.height, magicNumber: colorMagicNumber(a.magicNumber), max: max}, nil
+}
+
+// ShiftHue rotates the hue of every pixel by the given number of degrees,
+// keeping saturation and lightness.
+func (ppm *PPM) ShiftHue(degrees float64) {
+	max := ppm.max
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			c := ppm.data[y][x].ToHSL(max)
+			c.H = normalizeHue(c.H + degrees)
+			ppm.data[y][x] = c.ToPixel(max)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// testColors samples the RGB cube with maxval 255.
+func testColors() []Pixel {
+	var colors []Pixel
+	for r := 0; r <= 255; r += 51 {
+		for g := 0; g <= 255; g += 51 {
+			for b := 0; b <= 255; b += 51 {
+				colors = append(colors, Pixel{uint16(r), uint16(g), uint16(b)})
+			}
+		}
+	}
+	return colors
+}
+
+func TestColorRoundTrips(t *testing.T) {
+	conversions := map[string]func(p Pixel) Pixel{
+		"HSV":         func(p Pixel) Pixel { return p.ToHSV(255).ToPixel(255) },
+		"HSL":         func(p Pixel) Pixel { return p.ToHSL(255).ToPixel(255) },
+		"YCbCr BT601": func(p Pixel) Pixel { return p.ToYCbCr(255, BT601).ToPixel(255, BT601) },
+		"YCbCr BT709": func(p Pixel) Pixel { return p.ToYCbCr(255, BT709).ToPixel(255, BT709) },
+		"XYZ":         func(p Pixel) Pixel { return p.ToXYZ(255).ToPixel(255) },
+		"Lab":         func(p Pixel) Pixel { return p.ToLab(255).ToPixel(255) },
+		"LCh":         func(p Pixel) Pixel { return p.ToLCh(255).ToPixel(255) },
+	}
+	for name, convert := range conversions {
+		for _, p := range testColors() {
+			if got := convert(p); got != p {
+				t.Fatalf("%s: %v came back as %v", name, p, got)
+			}
+		}
+	}
+}
+
+func TestLabReferenceValues(t *testing.T) {
+	tests := []struct {
+		pixel Pixel
+		want  Lab
+	}{
+		{Pixel{255, 255, 255}, Lab{100, 0, 0}},
+		{Pixel{0, 0, 0}, Lab{0, 0, 0}},
+		{Pixel{255, 0, 0}, Lab{53.2408, 80.0925, 67.2032}},
+		{Pixel{0, 0, 255}, Lab{32.2970, 79.1875, -107.8602}},
+	}
+	for _, tt := range tests {
+		got := tt.pixel.ToLab(255)
+		if DeltaE76(got, tt.want) > 0.01 {
+			t.Errorf("%v: got %+v, want %+v", tt.pixel, got, tt.want)
+		}
+	}
+}
+
+func TestDeltaE(t *testing.T) {
+	a := Lab{50, 2.6772, -79.7751}
+	b := Lab{50, 0, -82.7485}
+	if got := DeltaE76(a, b); math.Abs(got-4.0011) > 1e-4 {
+		t.Errorf("DeltaE76: got %.4f, want 4.0011", got)
+	}
+	if got := DeltaE94(a, b); math.Abs(got-1.3950) > 1e-4 {
+		t.Errorf("DeltaE94: got %.4f, want 1.3950", got)
+	}
+	if got := DeltaE94(a, a); got != 0 {
+		t.Errorf("DeltaE94 of a color with itself: got %g", got)
+	}
+}
+
+func TestHSVReferenceValues(t *testing.T) {
+	c := Pixel{255, 128, 0}.ToHSV(255)
+	if math.Abs(c.H-30.1176) > 1e-3 || c.S != 1 || c.V != 1 {
+		t.Fatalf("got %+v", c)
+	}
+	if got := (HSV{H: 240, S: 1, V: 1}).ToPixel(255); got != (Pixel{0, 0, 255}) {
+		t.Fatalf("blue: got %v", got)
+	}
+}
+
+func TestSplitMergePlanes(t *testing.T) {
+	ppm := newTestPPM(4, 4, func(x, y int) Pixel { return testColors()[(x*7+y*31)%216] })
+	for _, space := range []ColorSpace{SpaceRGB, SpaceYCbCr601, SpaceHSV} {
+		a, b, c := ppm.SplitPlanes(space)
+		merged, err := MergePlanes(space, a, b, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := range merged.data {
+			for x := range merged.data[y] {
+				p, q := merged.data[y][x], ppm.data[y][x]
+				// Planes hold 8-bit samples: a hue step is about 1.4 degrees
+				if abs(int(p.R)-int(q.R)) > 4 || abs(int(p.G)-int(q.G)) > 4 || abs(int(p.B)-int(q.B)) > 4 {
+					t.Fatalf("space %d, pixel (%d, %d): got %v, want %v", space, x, y, p, q)
+				}
+			}
+		}
+	}
+}
+
+func TestMergePlanesMismatchedMax(t *testing.T) {
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{10, 20, 30} })
+	a, b, c := ppm.SplitPlanes(SpaceRGB)
+	c.max = 100
+	if _, err := MergePlanes(SpaceRGB, a, b, c); err == nil {
+		t.Fatal("planes with different max values accepted")
+	}
+}
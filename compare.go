@@ -0,0 +1,243 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metrics holds the differences between two images of the same size.
+type Metrics struct {
+	// MSE is the mean squared error, in sample units.
+	MSE float64
+	// PSNR is the peak signal-to-noise ratio in decibels, +Inf for identical
+	// images.
+	PSNR float64
+	// SSIM is the mean structural similarity, 1 for identical images.
+	SSIM float64
+	// MSSSIM is the multi-scale structural similarity over up to five scales.
+	MSSSIM float64
+	// MaxAbsError is the largest difference between two samples.
+	MaxAbsError int
+}
+
+// Weights of the five MS-SSIM scales (Wang, Simoncelli and Bovik)
+var msssimWeights = []float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+
+func checkComparable(aw, ah, amax, bw, bh, bmax int) error {
+	if aw != bw || ah != bh {
+		return fmt.Errorf("image sizes do not match: %dx%d and %dx%d", aw, ah, bw, bh)
+	}
+	if amax != bmax {
+		return fmt.Errorf("image max values do not match: %d and %d", amax, bmax)
+	}
+	return nil
+}
+
+// planeMetrics accumulates the squared error and the largest error of two
+// planes.
+func planeMetrics(a, b [][]uint16) (float64, int) {
+	sum, maxErr := 0.0, 0
+	for y := range a {
+		for x := range a[y] {
+			d := abs(int(a[y][x]) - int(b[y][x]))
+			sum += float64(d * d)
+			if d > maxErr {
+				maxErr = d
+			}
+		}
+	}
+	return sum, maxErr
+}
+
+// ssimComponents returns the mean luminance term and the mean
+// contrast-structure term of SSIM, with an 11x11 Gaussian window of sigma 1.5.
+func ssimComponents(a, b [][]float64, width, height, max int) (float64, float64) {
+	c1 := math.Pow(0.01*float64(max), 2)
+	c2 := math.Pow(0.03*float64(max), 2)
+	k := GaussianKernel(1.5)
+	blur := func(p [][]float64) [][]float64 {
+		return convolvePlane(p, width, height, k, EdgeMirror, 0)
+	}
+	product := func(p, q [][]float64) [][]float64 {
+		out := newGrid[float64](width, height)
+		for y := range out {
+			for x := range out[y] {
+				out[y][x] = p[y][x] * q[y][x]
+			}
+		}
+		return out
+	}
+
+	muA, muB := blur(a), blur(b)
+	aa, bb, ab := blur(product(a, a)), blur(product(b, b)), blur(product(a, b))
+	lum, cs := 0.0, 0.0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			ma, mb := muA[y][x], muB[y][x]
+			varA := aa[y][x] - ma*ma
+			varB := bb[y][x] - mb*mb
+			cov := ab[y][x] - ma*mb
+			lum += (2*ma*mb + c1) / (ma*ma + mb*mb + c1)
+			cs += (2*cov + c2) / (varA + varB + c2)
+		}
+	}
+	n := float64(width * height)
+	return lum / n, cs / n
+}
+
+// halvePlane averages 2x2 blocks, dropping an odd last row or column.
+func halvePlane(p [][]float64, width, height int) ([][]float64, int, int) {
+	w, h := width/2, height/2
+	out := newGrid[float64](w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out[y][x] = (p[2*y][2*x] + p[2*y][2*x+1] + p[2*y+1][2*x] + p[2*y+1][2*x+1]) / 4
+		}
+	}
+	return out, w, h
+}
+
+// planeSSIM returns the SSIM and MS-SSIM of two planes. MS-SSIM uses fewer
+// scales, with renormalized weights, when the image is too small for five.
+func planeSSIM(a, b [][]uint16, width, height, max int) (float64, float64) {
+	fa, fb := toFloatPlane(a, width, height), toFloatPlane(b, width, height)
+	lum, cs := ssimComponents(fa, fb, width, height, max)
+	ssim := lum * cs
+
+	scales := 1
+	// Every scale must hold at least one 11x11 window
+	for w, h := width/2, height/2; scales < len(msssimWeights) && w >= 11 && h >= 11; w, h = w/2, h/2 {
+		scales++
+	}
+	weights := msssimWeights[:scales]
+	total := 0.0
+	for _, wt := range weights {
+		total += wt
+	}
+
+	msssim := 1.0
+	w, h := width, height
+	for s, wt := range weights {
+		if s > 0 {
+			fa, _, _ = halvePlane(fa, w, h)
+			fb, w, h = halvePlane(fb, w, h)
+			lum, cs = ssimComponents(fa, fb, w, h, max)
+		}
+		// Negative values would give NaN with a fractional exponent
+		msssim *= math.Pow(math.Max(cs, 0), wt/total)
+		if s == scales-1 {
+			msssim *= math.Pow(math.Max(lum, 0), wt/total)
+		}
+	}
+	return ssim, msssim
+}
+
+// comparePlanes computes the metrics over one or more pairs of planes.
+func comparePlanes(a, b [][][]uint16, width, height, max int) Metrics {
+	var m Metrics
+	sum := 0.0
+	for i := range a {
+		s, e := planeMetrics(a[i], b[i])
+		sum += s
+		if e > m.MaxAbsError {
+			m.MaxAbsError = e
+		}
+		ssim, msssim := planeSSIM(a[i], b[i], width, height, max)
+		m.SSIM += ssim / float64(len(a))
+		m.MSSSIM += msssim / float64(len(a))
+	}
+	if n := width * height * len(a); n > 0 {
+		m.MSE = sum / float64(n)
+	}
+	if m.MSE == 0 {
+		m.PSNR = math.Inf(1)
+	} else {
+		m.PSNR = 10 * math.Log10(float64(max*max)/m.MSE)
+	}
+	return m
+}
+
+// ComparePGM measures the differences between two PGM images of the same
+// size and max value.
+func ComparePGM(a, b *PGM) (Metrics, error) {
+	if err := checkComparable(a.width, a.height, a.max, b.width, b.height, b.max); err != nil {
+		return Metrics{}, err
+	}
+	return comparePlanes([][][]uint16{a.data}, [][][]uint16{b.data}, a.width, a.height, a.max), nil
+}
+
+// ComparePPM measures the differences between two PPM images of the same
+// size and max value. The errors are taken over the three channels and the
+// similarities are averaged over them.
+func ComparePPM(a, b *PPM) (Metrics, error) {
+	if err := checkComparable(a.width, a.height, a.max, b.width, b.height, b.max); err != nil {
+		return Metrics{}, err
+	}
+	ar, ag, ab := a.Channels()
+	br, bg, bb := b.Channels()
+	pa := [][][]uint16{ar.data, ag.data, ab.data}
+	pb := [][][]uint16{br.data, bg.data, bb.data}
+	return comparePlanes(pa, pb, a.width, a.height, a.max), nil
+}
+
+// diffImage builds the highlight image: pixels where differs is true are
+// red, the others show a faded gray copy of the first image.
+func diffImage(width, height int, gray func(x, y int) float64, differs func(x, y int) bool) (*PPM, int) {
+	data := newGrid[Pixel](width, height)
+	count := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if differs(x, y) {
+				data[y][x] = Pixel{255, 0, 0}
+				count++
+				continue
+			}
+			// Gray faded towards white so that the red stands out
+			v := uint16(math.Round(191 + 64*gray(x, y)))
+			data[y][x] = Pixel{v, v, v}
+		}
+	}
+	return &PPM{data: data, width: width, height: height, magicNumber: "P6", max: 255}, count
+}
+
+// DiffPGM returns an image highlighting in red the pixels of a and b that
+// differ by more than threshold, along with their count.
+func DiffPGM(a, b *PGM, threshold int) (*PPM, int, error) {
+	if err := checkComparable(a.width, a.height, a.max, b.width, b.height, b.max); err != nil {
+		return nil, 0, err
+	}
+	img, count := diffImage(a.width, a.height,
+		func(x, y int) float64 {
+			if a.max == 0 {
+				return 0
+			}
+			return float64(a.data[y][x]) / float64(a.max)
+		},
+		func(x, y int) bool {
+			return abs(int(a.data[y][x])-int(b.data[y][x])) > threshold
+		})
+	return img, count, nil
+}
+
+// DiffPPM returns an image highlighting in red the pixels of a and b where
+// one channel differs by more than threshold, along with their count.
+func DiffPPM(a, b *PPM, threshold int) (*PPM, int, error) {
+	if err := checkComparable(a.width, a.height, a.max, b.width, b.height, b.max); err != nil {
+		return nil, 0, err
+	}
+	img, count := diffImage(a.width, a.height,
+		func(x, y int) float64 {
+			if a.max == 0 {
+				return 0
+			}
+			p := a.data[y][x]
+			return (float64(p.R) + float64(p.G) + float64(p.B)) / (3 * float64(a.max))
+		},
+		func(x, y int) bool {
+			p, q := a.data[y][x], b.data[y][x]
+			return abs(int(p.R)-int(q.R)) > threshold ||
+				abs(int(p.G)-int(q.G)) > threshold ||
+				abs(int(p.B)-int(q.B)) > threshold
+		})
+	return img, count, nil
+}
@@ -0,0 +1,97 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareIdentical(t *testing.T) {
+	pgm := newTestPGM(40, 30, func(x, y int) uint16 { return uint16((x*7 + y*3) % 256) })
+	m, err := ComparePGM(pgm, pgm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MSE != 0 || !math.IsInf(m.PSNR, 1) || m.MaxAbsError != 0 {
+		t.Fatalf("identical images: %+v", m)
+	}
+	if math.Abs(m.SSIM-1) > 1e-9 || math.Abs(m.MSSSIM-1) > 1e-9 {
+		t.Fatalf("identical images: SSIM %g, MS-SSIM %g", m.SSIM, m.MSSSIM)
+	}
+}
+
+func TestComparePSNR(t *testing.T) {
+	a := newTestPGM(8, 8, func(x, y int) uint16 { return 100 })
+	b := newTestPGM(8, 8, func(x, y int) uint16 { return 110 })
+	m, err := ComparePGM(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MSE != 100 || m.MaxAbsError != 10 {
+		t.Fatalf("MSE %g, max error %d, want 100 and 10", m.MSE, m.MaxAbsError)
+	}
+	if want := 10 * math.Log10(255*255/100.0); math.Abs(m.PSNR-want) > 1e-9 {
+		t.Fatalf("PSNR %g, want %g", m.PSNR, want)
+	}
+	// Flat images only differ by their luminance term.
+	c1 := math.Pow(0.01*255, 2)
+	want := (2*100*110 + c1) / (100*100 + 110*110 + c1)
+	if math.Abs(m.SSIM-want) > 1e-9 {
+		t.Fatalf("SSIM %g, want %g", m.SSIM, want)
+	}
+	// An 8x8 image is too small for a second scale.
+	if math.Abs(m.MSSSIM-m.SSIM) > 1e-9 {
+		t.Fatalf("MS-SSIM %g, want %g", m.MSSSIM, m.SSIM)
+	}
+}
+
+func TestComparePPMChannels(t *testing.T) {
+	a := newTestPPM(4, 4, func(x, y int) Pixel { return Pixel{10, 20, 30} })
+	b := newTestPPM(4, 4, func(x, y int) Pixel { return Pixel{10, 20, 36} })
+	m, err := ComparePPM(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MSE != 12 || m.MaxAbsError != 6 {
+		t.Fatalf("MSE %g, max error %d, want 12 and 6", m.MSE, m.MaxAbsError)
+	}
+}
+
+func TestCompareMismatch(t *testing.T) {
+	a := newTestPGM(4, 4, func(x, y int) uint16 { return 0 })
+	if _, err := ComparePGM(a, newTestPGM(4, 5, func(x, y int) uint16 { return 0 })); err == nil {
+		t.Error("expected an error for different sizes")
+	}
+	b := newTestPGM(4, 4, func(x, y int) uint16 { return 0 })
+	b.max = 15
+	if _, err := ComparePGM(a, b); err == nil {
+		t.Error("expected an error for different max values")
+	}
+	if _, _, err := DiffPGM(a, b, 0); err == nil {
+		t.Error("expected an error from DiffPGM")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := newTestPGM(3, 1, func(x, y int) uint16 { return 255 })
+	b := newTestPGM(3, 1, func(x, y int) uint16 { return []uint16{255, 250, 0}[x] })
+	img, count, err := DiffPGM(a, b, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("got %d differing pixels, want 1", count)
+	}
+	if img.data[0][2] != (Pixel{255, 0, 0}) || img.data[0][0] != (Pixel{255, 255, 255}) {
+		t.Fatalf("diff image %v", img.data[0])
+	}
+
+	p := newTestPPM(2, 1, func(x, y int) Pixel { return Pixel{0, 0, 0} })
+	q := newTestPPM(2, 1, func(x, y int) Pixel { return Pixel{0, uint16(x * 9), 0} })
+	img, count, err = DiffPPM(p, q, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || img.data[0][1] != (Pixel{255, 0, 0}) || img.data[0][0] != (Pixel{191, 191, 191}) {
+		t.Fatalf("got %d differences, image %v", count, img.data[0])
+	}
+}
@@ -0,0 +1,159 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// CompositeOperator is a Porter-Duff operator. A PPM destination has no alpha
+// channel and is always opaque, which collapses some operators; the results
+// below are given for a source alpha αs.
+type CompositeOperator int
+
+const (
+	// OpOver draws the source over the destination: src×αs + dst×(1-αs).
+	OpOver CompositeOperator = iota
+	// OpIn keeps the source only where the destination is present. On an
+	// opaque destination that is everywhere, and the result is src×αs: the
+	// destination is dropped and the source is faded towards black.
+	OpIn
+	// OpOut keeps the source only where the destination is absent. On an
+	// opaque destination that is nowhere, so the area is cleared to black.
+	OpOut
+	// OpAtop draws the source over the destination, inside the destination.
+	// On an opaque destination it gives the same result as OpOver.
+	OpAtop
+	// OpXor keeps the parts of the source and destination that do not overlap.
+	// On an opaque destination the source is dropped and the destination is
+	// faded towards black: dst×(1-αs).
+	OpXor
+)
+
+// BlendMode tells how source and destination colors are mixed where they
+// overlap.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendDifference
+	BlendAdd
+	BlendSubtract
+)
+
+// CompositeOptions controls Composite.
+type CompositeOptions struct {
+	Operator CompositeOperator
+	Blend    BlendMode
+	// Opacity of the source in [0, 1]. It is only used when OpacitySet is
+	// true, so that a zero CompositeOptions draws the source fully opaque.
+	Opacity float64
+	// OpacitySet tells that Opacity holds the opacity to use, including 0 for
+	// a transparent source.
+	OpacitySet bool
+	// Mask, when set, gives a per-pixel alpha for the source. It must have the
+	// size of the source; 0 is transparent and maxval opaque.
+	Mask *PGM
+	// Stencil, when set, limits drawing to the source pixels that are black
+	// (true) in it. It must have the size of the source.
+	Stencil *PBM
+}
+
+// blend mixes a destination and a source component, both in [0, 1].
+func (m BlendMode) blend(cd, cs float64) float64 {
+	switch m {
+	case BlendMultiply:
+		return cd * cs
+	case BlendScreen:
+		return cd + cs - cd*cs
+	case BlendOverlay:
+		if cd <= 0.5 {
+			return 2 * cs * cd
+		}
+		return 1 - 2*(1-cs)*(1-cd)
+	case BlendDarken:
+		return math.Min(cd, cs)
+	case BlendLighten:
+		return math.Max(cd, cs)
+	case BlendDifference:
+		return math.Abs(cd - cs)
+	case BlendAdd:
+		return math.Min(1, cd+cs)
+	case BlendSubtract:
+		return math.Max(0, cd-cs)
+	}
+	return cs
+}
+
+// combine applies the operator to a blended source component cs with alpha
+// as over an opaque destination component cd.
+func (op CompositeOperator) combine(cd, cs, as float64) float64 {
+	switch op {
+	case OpIn:
+		return as * cs
+	case OpOut:
+		return 0
+	case OpXor:
+		return (1 - as) * cd
+	}
+	// OpOver and OpAtop give the same result on an opaque destination
+	return as*cs + (1-as)*cd
+}
+
+// Composite draws src onto dst with its top-left corner at the given point,
+// using a Porter-Duff operator and a blend mode. Only the area where both
+// images overlap is touched.
+//
+// PPM images have no alpha channel, so the destination is taken as opaque and
+// the result is flattened over black: OpOver and OpAtop behave alike, OpIn
+// keeps only the source, OpOut clears the area and OpXor only fades the
+// destination.
+func Composite(dst *PPM, src *PPM, at Point, opts CompositeOptions) error {
+	if opts.Mask != nil && (opts.Mask.width != src.width || opts.Mask.height != src.height) {
+		return fmt.Errorf("mask size does not match the source")
+	}
+	if opts.Stencil != nil && (opts.Stencil.width != src.width || opts.Stencil.height != src.height) {
+		return fmt.Errorf("stencil size does not match the source")
+	}
+	if opts.OpacitySet && (opts.Opacity < 0 || opts.Opacity > 1) {
+		return fmt.Errorf("opacity must be between 0 and 1: %g", opts.Opacity)
+	}
+	opacity := 1.0
+	if opts.OpacitySet {
+		opacity = opts.Opacity
+	}
+
+	area := Rect(at.X, at.Y, at.X+src.width, at.Y+src.height).Intersect(dst.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		sy := y - at.Y
+		for x := area.Min.X; x < area.Max.X; x++ {
+			sx := x - at.X
+			if opts.Stencil != nil && !opts.Stencil.data[sy][sx] {
+				continue
+			}
+			alpha := opacity
+			if opts.Mask != nil {
+				if opts.Mask.max <= 0 {
+					alpha = 0
+				} else {
+					alpha *= clampUnit(float64(opts.Mask.data[sy][sx]) / float64(opts.Mask.max))
+				}
+			}
+
+			d := &dst.data[y][x]
+			s := src.data[sy][sx]
+			dr, dg, db := d.normalize(dst.max)
+			sr, sg, sb := s.normalize(src.max)
+			*d = pixelFromUnit(
+				opts.Operator.combine(dr, opts.Blend.blend(dr, sr), alpha),
+				opts.Operator.combine(dg, opts.Blend.blend(dg, sg), alpha),
+				opts.Operator.combine(db, opts.Blend.blend(db, sb), alpha),
+				dst.max)
+		}
+	}
+	return nil
+}
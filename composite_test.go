@@ -0,0 +1,103 @@
+package Netpbm
+
+import "testing"
+
+func compositeOnce(t *testing.T, dst, src Pixel, opts CompositeOptions) Pixel {
+	t.Helper()
+	d := newTestPPM(1, 1, func(x, y int) Pixel { return dst })
+	s := newTestPPM(1, 1, func(x, y int) Pixel { return src })
+	if err := Composite(d, s, Point{0, 0}, opts); err != nil {
+		t.Fatal(err)
+	}
+	return d.data[0][0]
+}
+
+func TestCompositeZeroOptionsPaint(t *testing.T) {
+	if got := compositeOnce(t, Pixel{10, 20, 30}, Pixel{200, 100, 50}, CompositeOptions{}); got != (Pixel{200, 100, 50}) {
+		t.Fatalf("zero options: got %v, want the source", got)
+	}
+}
+
+func TestCompositeOpacity(t *testing.T) {
+	dst, src := Pixel{0, 100, 200}, Pixel{200, 100, 0}
+	if got := compositeOnce(t, dst, src, CompositeOptions{Opacity: 0, OpacitySet: true}); got != dst {
+		t.Fatalf("opacity 0: got %v, want the destination", got)
+	}
+	if got := compositeOnce(t, dst, src, CompositeOptions{Opacity: 0.5, OpacitySet: true}); got != (Pixel{100, 100, 100}) {
+		t.Fatalf("opacity 0.5: got %v", got)
+	}
+	d := newTestPPM(1, 1, func(x, y int) Pixel { return dst })
+	if err := Composite(d, d, Point{}, CompositeOptions{Opacity: 2, OpacitySet: true}); err == nil {
+		t.Fatal("opacity 2 accepted")
+	}
+}
+
+func TestCompositeOperatorsOnOpaqueDestination(t *testing.T) {
+	dst, src := Pixel{100, 100, 100}, Pixel{200, 200, 200}
+	half := func(op CompositeOperator) CompositeOptions {
+		return CompositeOptions{Operator: op, Opacity: 0.5, OpacitySet: true}
+	}
+	tests := []struct {
+		op   CompositeOperator
+		want Pixel
+	}{
+		{OpOver, Pixel{150, 150, 150}},
+		{OpAtop, Pixel{150, 150, 150}},
+		{OpIn, Pixel{100, 100, 100}}, // src × αs
+		{OpOut, Pixel{0, 0, 0}},      // cleared to black
+		{OpXor, Pixel{50, 50, 50}},   // dst × (1 - αs)
+	}
+	for _, tt := range tests {
+		if got := compositeOnce(t, dst, src, half(tt.op)); got != tt.want {
+			t.Errorf("operator %d: got %v, want %v", tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestCompositeBlendModes(t *testing.T) {
+	dst, src := Pixel{51, 204, 255}, Pixel{255, 102, 51}
+	tests := []struct {
+		mode BlendMode
+		want Pixel
+	}{
+		{BlendMultiply, Pixel{51, 82, 51}},
+		{BlendScreen, Pixel{255, 224, 255}},
+		{BlendDarken, Pixel{51, 102, 51}},
+		{BlendLighten, Pixel{255, 204, 255}},
+		{BlendDifference, Pixel{204, 102, 204}},
+		{BlendAdd, Pixel{255, 255, 255}},
+		{BlendSubtract, Pixel{0, 102, 204}},
+	}
+	for _, tt := range tests {
+		if got := compositeOnce(t, dst, src, CompositeOptions{Blend: tt.mode}); got != tt.want {
+			t.Errorf("blend %d: got %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCompositeMaskStencilAndClipping(t *testing.T) {
+	dst := newTestPPM(3, 1, func(x, y int) Pixel { return Pixel{} })
+	src := newTestPPM(2, 1, func(x, y int) Pixel { return Pixel{255, 255, 255} })
+	mask := newTestPGM(2, 1, func(x, y int) uint16 { return []uint16{255, 51}[x] })
+	if err := Composite(dst, src, Point{1, 0}, CompositeOptions{Mask: mask}); err != nil {
+		t.Fatal(err)
+	}
+	if dst.data[0][0] != (Pixel{}) || dst.data[0][1] != (Pixel{255, 255, 255}) || dst.data[0][2] != (Pixel{51, 51, 51}) {
+		t.Fatalf("mask: got %v", dst.data[0])
+	}
+
+	dst = newTestPPM(2, 1, func(x, y int) Pixel { return Pixel{} })
+	stencil := newTestPBM(".#")
+	if err := Composite(dst, src, Point{}, CompositeOptions{Stencil: stencil}); err != nil {
+		t.Fatal(err)
+	}
+	if dst.data[0][0] != (Pixel{}) || dst.data[0][1] != (Pixel{255, 255, 255}) {
+		t.Fatalf("stencil: got %v", dst.data[0])
+	}
+	if err := Composite(dst, src, Point{-5, 0}, CompositeOptions{}); err != nil {
+		t.Fatalf("source outside the destination: %v", err)
+	}
+	if err := Composite(dst, src, Point{}, CompositeOptions{Stencil: newTestPBM("#")}); err == nil {
+		t.Fatal("stencil of the wrong size accepted")
+	}
+}
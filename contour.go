@@ -0,0 +1,348 @@
+package Netpbm
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Contour is a closed border between black and white pixels, given by the
+// coordinates of the black pixels along it.
+type Contour struct {
+	Points []Point
+	// Outline is the same border following the pixel edges: its points are
+	// pixel corners, (x, y) being the top-left corner of pixel (x, y). It
+	// turns clockwise around black regions and counterclockwise around holes,
+	// so its signed area is the number of pixels it encloses.
+	Outline []Point
+	// Hole is true for the border of a white region inside a black one.
+	Hole bool
+	// Parent is the index of the enclosing contour, or -1 for an outermost
+	// contour.
+	Parent int
+}
+
+// clockwise8 lists the 8 neighbour offsets in clockwise order on screen,
+// starting east.
+var clockwise8 = [8]Point{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+
+func directionOf(dx, dy int) int {
+	for i, d := range clockwise8 {
+		if d.X == dx && d.Y == dy {
+			return i
+		}
+	}
+	return 0
+}
+
+// Contours traces the outer borders of the black regions and the borders of
+// their holes with the Suzuki-Abe algorithm, using 8-connectivity for black
+// pixels. Each contour records its enclosing contour, so the nesting of
+// shapes and holes is kept.
+func (pbm *PBM) Contours() []Contour {
+	w, h := pbm.width+2, pbm.height+2
+	// Image framed with a white border: 1 for black, then border numbers
+	f := newGrid[int](w, h)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] {
+				f[y+1][x+1] = 1
+			}
+		}
+	}
+
+	var contours []Contour
+	// Border n (n >= 2) is contours[n-2]; 1 is the frame
+	holeOf := func(nbd int) bool {
+		if nbd <= 1 {
+			return true
+		}
+		return contours[nbd-2].Hole
+	}
+	parentOf := func(nbd int) int {
+		if nbd <= 1 {
+			return -1
+		}
+		return contours[nbd-2].Parent
+	}
+
+	nbd := 1
+	for y := 1; y < h-1; y++ {
+		lnbd := 1
+		for x := 1; x < w-1; x++ {
+			var start Point
+			var hole bool
+			switch {
+			case f[y][x] == 1 && f[y][x-1] == 0:
+				start, hole = Point{x - 1, y}, false
+			case f[y][x] >= 1 && f[y][x+1] == 0:
+				start, hole = Point{x + 1, y}, true
+				if f[y][x] > 1 {
+					lnbd = f[y][x]
+				}
+			default:
+				if f[y][x] != 0 && f[y][x] != 1 {
+					lnbd = abs(f[y][x])
+				}
+				continue
+			}
+
+			nbd++
+			parent := -1
+			if hole == holeOf(lnbd) {
+				parent = parentOf(lnbd)
+			} else if lnbd > 1 {
+				parent = lnbd - 2
+			}
+			contour := Contour{Hole: hole, Parent: parent}
+			contour.Points = traceBorder(f, Point{x, y}, start, nbd)
+			if hole {
+				contour.Outline = pbm.traceCracks(Point{x, y - 1}, crackSouth)
+			} else {
+				contour.Outline = pbm.traceCracks(Point{x - 1, y}, crackNorth)
+			}
+			for i := range contour.Points {
+				contour.Points[i].X--
+				contour.Points[i].Y--
+			}
+			contours = append(contours, contour)
+
+			if f[y][x] != 1 {
+				lnbd = abs(f[y][x])
+			}
+		}
+	}
+	return contours
+}
+
+// traceBorder follows a border starting at p, whose white neighbour is from,
+// marking the border pixels with nbd as in Suzuki-Abe, and returns the
+// visited pixels.
+func traceBorder(f [][]int, p, from Point, nbd int) []Point {
+	// 3.1: first non-zero pixel clockwise
+	dir := directionOf(from.X-p.X, from.Y-p.Y)
+	found := -1
+	for i := 0; i < 8; i++ {
+		d := (dir + i) % 8
+		if f[p.Y+clockwise8[d].Y][p.X+clockwise8[d].X] != 0 {
+			found = d
+			break
+		}
+	}
+	if found < 0 {
+		f[p.Y][p.X] = -nbd
+		return []Point{p}
+	}
+
+	p1 := Point{p.X + clockwise8[found].X, p.Y + clockwise8[found].Y}
+	p2, p3 := p1, p
+	var points []Point
+	for {
+		// 3.3: search counterclockwise from the pixel following p2
+		back := directionOf(p2.X-p3.X, p2.Y-p3.Y)
+		eastChecked := false
+		var p4 Point
+		for i := 1; i <= 8; i++ {
+			d := (back - i + 16) % 8
+			q := Point{p3.X + clockwise8[d].X, p3.Y + clockwise8[d].Y}
+			if d == 0 {
+				eastChecked = true
+			}
+			if f[q.Y][q.X] != 0 {
+				p4 = q
+				break
+			}
+		}
+
+		// 3.4
+		if eastChecked && f[p3.Y][p3.X+1] == 0 {
+			f[p3.Y][p3.X] = -nbd
+		} else if f[p3.Y][p3.X] == 1 {
+			f[p3.Y][p3.X] = nbd
+		}
+		points = append(points, p3)
+
+		// 3.5
+		if p4 == p && p3 == p1 {
+			return points
+		}
+		p2, p3 = p3, p4
+	}
+}
+
+// Crack directions, clockwise on screen.
+const (
+	crackEast = iota
+	crackSouth
+	crackWest
+	crackNorth
+)
+
+var crackSteps = [4]Point{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// crackAhead gives, for each direction, the offsets from a corner to the
+// pixels ahead on the left and on the right.
+var crackAhead = [4][2]Point{
+	{{0, -1}, {0, 0}},
+	{{0, 0}, {-1, 0}},
+	{{-1, 0}, {-1, -1}},
+	{{-1, -1}, {0, -1}},
+}
+
+// traceCracks follows the pixel edges from the corner start, leaving the
+// first step in direction dir, and keeps the black pixels on its right until
+// it comes back. Diagonal black pixels are joined, matching the
+// 8-connectivity of Contours. It returns the corners where the path turns.
+func (pbm *PBM) traceCracks(start Point, dir int) []Point {
+	black := func(p Point) bool {
+		return p.X >= 0 && p.Y >= 0 && p.X < pbm.width && p.Y < pbm.height && pbm.data[p.Y][p.X]
+	}
+	var points []Point
+	c, d := start, dir
+	for {
+		c = Point{c.X + crackSteps[d].X, c.Y + crackSteps[d].Y}
+		left := Point{c.X + crackAhead[d][0].X, c.Y + crackAhead[d][0].Y}
+		right := Point{c.X + crackAhead[d][1].X, c.Y + crackAhead[d][1].Y}
+		next := d
+		switch {
+		case black(left):
+			next = (d + 3) % 4
+		case black(right):
+		default:
+			next = (d + 1) % 4
+		}
+		if next != d {
+			points = append(points, c)
+		}
+		d = next
+		if c == start && d == dir {
+			return points
+		}
+	}
+}
+
+// SimplifyPolygon reduces a closed polygon with the Douglas-Peucker
+// algorithm, dropping the points closer than epsilon to the simplified
+// outline.
+func SimplifyPolygon(points []Point, epsilon float64) []Point {
+	if len(points) < 3 {
+		return append([]Point(nil), points...)
+	}
+	// Split the polygon at the point farthest from the first
+	far := 0
+	best := -1.0
+	for i, p := range points {
+		if d := math.Hypot(float64(p.X-points[0].X), float64(p.Y-points[0].Y)); d > best {
+			far, best = i, d
+		}
+	}
+	if far == 0 {
+		return []Point{points[0]}
+	}
+	first := douglasPeucker(points[:far+1], epsilon)
+	second := douglasPeucker(append(append([]Point(nil), points[far:]...), points[0]), epsilon)
+	return append(first[:len(first)-1], second[:len(second)-1]...)
+}
+
+// douglasPeucker simplifies an open polyline, keeping both ends.
+func douglasPeucker(points []Point, epsilon float64) []Point {
+	if len(points) < 3 {
+		return append([]Point(nil), points...)
+	}
+	a, b := points[0], points[len(points)-1]
+	index, dmax := 0, 0.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := segmentDistance(points[i], a, b); d > dmax {
+			index, dmax = i, d
+		}
+	}
+	if dmax <= epsilon {
+		return []Point{a, b}
+	}
+	left := douglasPeucker(points[:index+1], epsilon)
+	right := douglasPeucker(points[index:], epsilon)
+	return append(left[:len(left)-1], right...)
+}
+
+// segmentDistance returns the distance from p to the segment [a, b].
+func segmentDistance(p, a, b Point) float64 {
+	dx, dy := float64(b.X-a.X), float64(b.Y-a.Y)
+	px, py := float64(p.X-a.X), float64(p.Y-a.Y)
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(px, py)
+	}
+	t := math.Max(0, math.Min(1, (px*dx+py*dy)/length))
+	return math.Hypot(px-t*dx, py-t*dy)
+}
+
+// Simplify returns the contour, points and outline, simplified with
+// SimplifyPolygon.
+func (c Contour) Simplify(epsilon float64) Contour {
+	return Contour{
+		Points:  SimplifyPolygon(c.Points, epsilon),
+		Outline: SimplifyPolygon(c.Outline, epsilon),
+		Hole:    c.Hole,
+		Parent:  c.Parent,
+	}
+}
+
+// ContoursToSVG renders contours as an SVG document of the given size. All
+// contours go into one path filled with the even-odd rule, so holes stay
+// empty. The outlines are drawn along the pixel edges, so the path covers
+// exactly the black pixels; contours without an outline fall back to their
+// points, placed on pixel centers.
+func ContoursToSVG(width, height int, contours []Contour) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	b.WriteString("<path fill=\"black\" fill-rule=\"evenodd\" d=\"")
+	for i, c := range contours {
+		points, offset := c.Outline, 0.0
+		if len(points) == 0 {
+			points, offset = c.Points, 0.5
+		}
+		if len(points) == 0 {
+			continue
+		}
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		for j, p := range points {
+			if j == 0 {
+				b.WriteString("M")
+			} else {
+				b.WriteString(" L")
+			}
+			fmt.Fprintf(&b, "%g %g", float64(p.X)+offset, float64(p.Y)+offset)
+		}
+		b.WriteString(" Z")
+	}
+	b.WriteString("\"/>\n</svg>\n")
+	return b.String()
+}
+
+// SaveSVG traces the contours of the PBM image, simplifies them with the given
+// tolerance (0 keeps every point) and writes them to an SVG file.
+func (pbm *PBM) SaveSVG(filename string, epsilon float64) error {
+	contours := pbm.Contours()
+	if epsilon > 0 {
+		for i := range contours {
+			contours[i] = contours[i].Simplify(epsilon)
+		}
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	_, err = writer.WriteString(ContoursToSVG(pbm.width, pbm.height, contours))
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
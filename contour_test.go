@@ -0,0 +1,145 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// signedArea returns the shoelace area of a closed polygon, positive when it
+// turns clockwise on screen.
+func signedArea(points []Point) int {
+	sum := 0
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		sum += p.X*q.Y - q.X*p.Y
+	}
+	return sum / 2
+}
+
+func outlineArea(contours []Contour) int {
+	area := 0
+	for _, c := range contours {
+		area += signedArea(c.Outline)
+	}
+	return area
+}
+
+func TestContoursNesting(t *testing.T) {
+	pbm := newTestPBM(
+		".......",
+		".#####.",
+		".#...#.",
+		".#.#.#.",
+		".#...#.",
+		".#####.",
+		".......",
+	)
+	contours := pbm.Contours()
+	if len(contours) != 3 {
+		t.Fatalf("got %d contours, want 3", len(contours))
+	}
+	if contours[0].Hole || contours[0].Parent != -1 {
+		t.Fatalf("outer ring: %+v", contours[0])
+	}
+	if !contours[1].Hole || contours[1].Parent != 0 {
+		t.Fatalf("hole: hole %v parent %d", contours[1].Hole, contours[1].Parent)
+	}
+	if contours[2].Hole || contours[2].Parent != 1 {
+		t.Fatalf("dot: hole %v parent %d", contours[2].Hole, contours[2].Parent)
+	}
+	if len(contours[2].Points) != 1 || contours[2].Points[0] != (Point{3, 3}) {
+		t.Fatalf("dot points %v", contours[2].Points)
+	}
+	if len(contours[0].Points) != 16 {
+		t.Fatalf("outer ring has %d points, want 16", len(contours[0].Points))
+	}
+}
+
+func TestOutlineArea(t *testing.T) {
+	ring := newTestPBM(
+		"#####",
+		"#...#",
+		"#...#",
+		"#...#",
+		"#####",
+	)
+	contours := ring.Contours()
+	if got := outlineArea(contours); got != 16 {
+		t.Fatalf("ring area %d, want 16", got)
+	}
+	if got := signedArea(contours[0].Outline); got != 25 {
+		t.Fatalf("outer outline area %d, want 25", got)
+	}
+	want := []Point{{0, 0}, {5, 0}, {5, 5}, {0, 5}}
+	for i, p := range want {
+		if contours[0].Outline[i] != p {
+			t.Fatalf("outer outline %v, want %v", contours[0].Outline, want)
+		}
+	}
+
+	line := newTestPBM(".....", "#####", ".....")
+	if got := outlineArea(line.Contours()); got != 5 {
+		t.Fatalf("line area %d, want 5", got)
+	}
+}
+
+func TestOutlineAreaRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 30; i++ {
+		w, h := 3+rng.Intn(15), 3+rng.Intn(15)
+		pbm := &PBM{data: newGrid[bool](w, h), width: w, height: h, magicNumber: "P1"}
+		for y := range pbm.data {
+			for x := range pbm.data[y] {
+				pbm.data[y][x] = rng.Intn(2) == 0
+			}
+		}
+		if got, want := outlineArea(pbm.Contours()), pbm.bits().count(); got != want {
+			t.Fatalf("case %d: outline area %d, want %d black pixels", i, got, want)
+		}
+	}
+}
+
+func TestSimplifyPolygon(t *testing.T) {
+	var square []Point
+	for x := 0; x < 10; x++ {
+		square = append(square, Point{x, 0})
+	}
+	for y := 0; y < 10; y++ {
+		square = append(square, Point{10, y})
+	}
+	for x := 10; x > 0; x-- {
+		square = append(square, Point{x, 10})
+	}
+	for y := 10; y > 0; y-- {
+		square = append(square, Point{0, y})
+	}
+	got := SimplifyPolygon(square, 0.5)
+	if len(got) != 4 {
+		t.Fatalf("simplified to %v, want the 4 corners", got)
+	}
+	if signedArea(got) != 100 {
+		t.Fatalf("simplified area %d, want 100", signedArea(got))
+	}
+}
+
+func TestSaveSVG(t *testing.T) {
+	pbm := newTestPBM(
+		"##.",
+		"##.",
+	)
+	filename := filepath.Join(t.TempDir(), "out.svg")
+	if err := pbm.SaveSVG(filename, 0); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := string(content)
+	if !strings.Contains(svg, `width="3" height="2"`) || !strings.Contains(svg, `d="M0 0 L2 0 L2 2 L0 2 Z"`) {
+		t.Fatalf("unexpected SVG:\n%s", svg)
+	}
+}
@@ -0,0 +1,339 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Kernel is a convolution kernel. Its anchor is the center cell
+// (Width/2, Height/2).
+type Kernel struct {
+	Width, Height int
+	// Data holds the weights row by row.
+	Data []float64
+
+	// 1D factors when the kernel is separable
+	horizontal, vertical []float64
+}
+
+// EdgeMode tells which value is used for pixels outside the image.
+type EdgeMode int
+
+const (
+	// EdgeClamp repeats the nearest border pixel.
+	EdgeClamp EdgeMode = iota
+	// EdgeWrap wraps around to the opposite border.
+	EdgeWrap
+	// EdgeMirror reflects the image at its border.
+	EdgeMirror
+	// EdgeConstant uses a fixed value.
+	EdgeConstant
+)
+
+// ConvolveOptions controls a convolution.
+type ConvolveOptions struct {
+	// Normalize divides the result by the sum of the kernel weights, when it
+	// is not zero.
+	Normalize bool
+	// Bias is added to every result, in sample units.
+	Bias float64
+	// Edge selects how pixels outside the image are read.
+	Edge EdgeMode
+	// Constant is the sample value used outside the image with EdgeConstant.
+	Constant float64
+}
+
+// NewKernel creates a kernel from its weights given row by row.
+func NewKernel(width, height int, data []float64) (*Kernel, error) {
+	if width <= 0 || height <= 0 || len(data) != width*height {
+		return nil, fmt.Errorf("kernel needs %dx%d weights, got %d", width, height, len(data))
+	}
+	weights := make([]float64, len(data))
+	copy(weights, data)
+	return &Kernel{Width: width, Height: height, Data: weights}, nil
+}
+
+// NewSeparableKernel creates the kernel equal to the outer product of a
+// horizontal and a vertical vector. Convolutions with it run as two 1D passes.
+func NewSeparableKernel(horizontal, vertical []float64) *Kernel {
+	k := &Kernel{Width: len(horizontal), Height: len(vertical), Data: make([]float64, len(horizontal)*len(vertical))}
+	for j, v := range vertical {
+		for i, h := range horizontal {
+			k.Data[j*k.Width+i] = h * v
+		}
+	}
+	k.horizontal = append([]float64(nil), horizontal...)
+	k.vertical = append([]float64(nil), vertical...)
+	return k
+}
+
+// Sum returns the sum of the kernel weights.
+func (k *Kernel) Sum() float64 {
+	sum := 0.0
+	for _, w := range k.Data {
+		sum += w
+	}
+	return sum
+}
+
+// BoxKernel returns a (2*radius+1) square kernel of equal weights summing to 1.
+// A negative radius is treated as 0, which gives the identity kernel.
+func BoxKernel(radius int) *Kernel {
+	if radius < 0 {
+		radius = 0
+	}
+	size := 2*radius + 1
+	v := make([]float64, size)
+	for i := range v {
+		v[i] = 1 / float64(size)
+	}
+	return NewSeparableKernel(v, v)
+}
+
+// GaussianKernel returns a normalized Gaussian kernel with the given standard
+// deviation, truncated at three sigmas.
+func GaussianKernel(sigma float64) *Kernel {
+	v := gaussianVector(sigma)
+	return NewSeparableKernel(v, v)
+}
+
+func gaussianVector(sigma float64) []float64 {
+	if sigma <= 0 {
+		return []float64{1}
+	}
+	radius := int(math.Ceil(3 * sigma))
+	v := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range v {
+		d := float64(i - radius)
+		v[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += v[i]
+	}
+	for i := range v {
+		v[i] /= sum
+	}
+	return v
+}
+
+// SharpenKernel returns a 3x3 sharpening kernel.
+func SharpenKernel() *Kernel {
+	k, _ := NewKernel(3, 3, []float64{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	})
+	return k
+}
+
+// EmbossKernel returns a 3x3 emboss kernel lit from the top-left. Its
+// weights sum to 0, so flat areas give a zero response.
+func EmbossKernel() *Kernel {
+	k, _ := NewKernel(3, 3, []float64{
+		-2, -1, 0,
+		-1, 0, 1,
+		0, 1, 2,
+	})
+	return k
+}
+
+// LaplacianKernel returns the 3x3 Laplacian kernel (4-neighbour).
+func LaplacianKernel() *Kernel {
+	k, _ := NewKernel(3, 3, []float64{
+		0, 1, 0,
+		1, -4, 1,
+		0, 1, 0,
+	})
+	return k
+}
+
+// edgeIndex maps a coordinate that may fall outside [0, n) according to the
+// edge mode. ok is false when the constant value must be used instead.
+func edgeIndex(i, n int, mode EdgeMode) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch mode {
+	case EdgeWrap:
+		return ((i % n) + n) % n, true
+	case EdgeMirror:
+		i = ((i % (2 * n)) + 2*n) % (2 * n)
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i, true
+	case EdgeConstant:
+		return 0, false
+	}
+	return clamp(i, 0, n-1), true
+}
+
+// toFloatPlane copies a plane into floating point samples.
+func toFloatPlane(data [][]uint16, width, height int) [][]float64 {
+	out := newGrid[float64](width, height)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = float64(data[y][x])
+		}
+	}
+	return out
+}
+
+// fromFloatPlane rounds floating point samples and clamps them to [0, max].
+func fromFloatPlane(data [][]float64, width, height, max int) [][]uint16 {
+	out := newGrid[uint16](width, height)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = uint16(clamp(int(math.Round(data[y][x])), 0, max))
+		}
+	}
+	return out
+}
+
+// convolvePlane correlates a plane with the kernel (the kernel is not
+// flipped) and returns the raw results.
+func convolvePlane(src [][]float64, width, height int, k *Kernel, edge EdgeMode, constant float64) [][]float64 {
+	if k.horizontal != nil {
+		tmp := convolve1D(src, width, height, k.horizontal, true, edge, constant)
+		return convolve1D(tmp, width, height, k.vertical, false, edge, constant)
+	}
+
+	ax, ay := k.Width/2, k.Height/2
+	out := newGrid[float64](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for j := 0; j < k.Height; j++ {
+				sy, oky := edgeIndex(y+j-ay, height, edge)
+				for i := 0; i < k.Width; i++ {
+					w := k.Data[j*k.Width+i]
+					if w == 0 {
+						continue
+					}
+					sx, okx := edgeIndex(x+i-ax, width, edge)
+					if oky && okx {
+						sum += w * src[sy][sx]
+					} else {
+						sum += w * constant
+					}
+				}
+			}
+			out[y][x] = sum
+		}
+	}
+	return out
+}
+
+// convolve1D correlates a plane with a vector along rows or columns.
+func convolve1D(src [][]float64, width, height int, v []float64, horizontal bool, edge EdgeMode, constant float64) [][]float64 {
+	anchor := len(v) / 2
+	out := newGrid[float64](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum := 0.0
+			for i, w := range v {
+				var s float64
+				if horizontal {
+					sx, ok := edgeIndex(x+i-anchor, width, edge)
+					if ok {
+						s = src[y][sx]
+					} else {
+						s = constant
+					}
+				} else {
+					sy, ok := edgeIndex(y+i-anchor, height, edge)
+					if ok {
+						s = src[sy][x]
+					} else {
+						s = constant
+					}
+				}
+				sum += w * s
+			}
+			out[y][x] = sum
+		}
+	}
+	return out
+}
+
+// convolveSamples convolves a plane of samples and applies the options.
+func convolveSamples(data [][]uint16, width, height, max int, k *Kernel, opts ConvolveOptions) [][]uint16 {
+	out := convolvePlane(toFloatPlane(data, width, height), width, height, k, opts.Edge, opts.Constant)
+	scale := 1.0
+	if sum := k.Sum(); opts.Normalize && sum != 0 {
+		scale = 1 / sum
+	}
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = out[y][x]*scale + opts.Bias
+		}
+	}
+	return fromFloatPlane(out, width, height, max)
+}
+
+// Convolve filters the PGM image with a kernel. Like most image libraries it
+// computes a correlation: the kernel is applied as given, without flipping
+// it, so the weight at (i, j) multiplies the pixel at offset
+// (i-Width/2, j-Height/2). Flip asymmetric kernels to get a true convolution.
+func (pgm *PGM) Convolve(k *Kernel, opts ConvolveOptions) {
+	pgm.data = storeGrid(pgm.data, convolveSamples(pgm.data, pgm.width, pgm.height, pgm.max, k, opts))
+}
+
+// Convolve filters each channel of the PPM image with a kernel. As for PGM
+// images, the kernel is not flipped.
+func (ppm *PPM) Convolve(k *Kernel, opts ConvolveOptions) {
+	ppm.mapChannels(ppm.width, ppm.height, func(c Channel, plane [][]uint16) [][]uint16 {
+		return convolveSamples(plane, ppm.width, ppm.height, ppm.max, k, opts)
+	})
+}
+
+// BoxBlur averages each pixel with its neighbours within the given radius.
+func (pgm *PGM) BoxBlur(radius int) {
+	pgm.Convolve(BoxKernel(radius), ConvolveOptions{})
+}
+
+// GaussianBlur blurs the image with a Gaussian of the given standard deviation.
+func (pgm *PGM) GaussianBlur(sigma float64) {
+	pgm.Convolve(GaussianKernel(sigma), ConvolveOptions{})
+}
+
+// Sharpen enhances the edges of the image.
+func (pgm *PGM) Sharpen() {
+	pgm.Convolve(SharpenKernel(), ConvolveOptions{})
+}
+
+// Emboss gives the image a relief look. Flat areas become mid-gray.
+func (pgm *PGM) Emboss() {
+	pgm.Convolve(EmbossKernel(), ConvolveOptions{Bias: float64(pgm.max) / 2})
+}
+
+// Laplacian replaces the image with its Laplacian, offset so that a zero
+// response is mid-gray.
+func (pgm *PGM) Laplacian() {
+	pgm.Convolve(LaplacianKernel(), ConvolveOptions{Bias: float64(pgm.max) / 2})
+}
+
+// BoxBlur averages each pixel with its neighbours within the given radius.
+func (ppm *PPM) BoxBlur(radius int) {
+	ppm.Convolve(BoxKernel(radius), ConvolveOptions{})
+}
+
+// GaussianBlur blurs the image with a Gaussian of the given standard deviation.
+func (ppm *PPM) GaussianBlur(sigma float64) {
+	ppm.Convolve(GaussianKernel(sigma), ConvolveOptions{})
+}
+
+// Sharpen enhances the edges of the image.
+func (ppm *PPM) Sharpen() {
+	ppm.Convolve(SharpenKernel(), ConvolveOptions{})
+}
+
+// Emboss gives the image a relief look. Flat areas become mid-gray.
+func (ppm *PPM) Emboss() {
+	ppm.Convolve(EmbossKernel(), ConvolveOptions{Bias: float64(ppm.max) / 2})
+}
+
+// Laplacian replaces each channel with its Laplacian, offset so that a zero
+// response is mid-gray.
+func (ppm *PPM) Laplacian() {
+	ppm.Convolve(LaplacianKernel(), ConvolveOptions{Bias: float64(ppm.max) / 2})
+}
@@ -0,0 +1,112 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEmbossFlat(t *testing.T) {
+	for _, v := range []uint16{0, 200} {
+		pgm := newTestPGM(4, 4, func(x, y int) uint16 { return v })
+		pgm.Emboss()
+		if got := pgm.data[1][1]; got != 128 {
+			t.Fatalf("emboss of a flat %d image: got %d, want 128", v, got)
+		}
+	}
+}
+
+func TestBoxBlurNegativeRadius(t *testing.T) {
+	pgm := newTestPGM(3, 3, func(x, y int) uint16 { return uint16(10*y + x) })
+	pgm.BoxBlur(-1)
+	if want := newTestPGM(3, 3, func(x, y int) uint16 { return uint16(10*y + x) }); !reflect.DeepEqual(pgm.data, want.data) {
+		t.Fatalf("got %v", pgm.data)
+	}
+}
+
+func TestConvolveIsCorrelation(t *testing.T) {
+	// The kernel reads the pixel on the right of the anchor.
+	k, err := NewKernel(3, 1, []float64{0, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgm := newTestPGM(4, 1, func(x, y int) uint16 { return uint16(10 * (x + 1)) })
+	pgm.Convolve(k, ConvolveOptions{})
+	if want := []uint16{20, 30, 40, 40}; !reflect.DeepEqual(pgm.data[0], want) {
+		t.Fatalf("got %v, want %v", pgm.data[0], want)
+	}
+}
+
+func TestConvolveEdgeModes(t *testing.T) {
+	k, _ := NewKernel(3, 1, []float64{1, 0, 0})
+	tests := []struct {
+		edge EdgeMode
+		want uint16
+	}{
+		{EdgeClamp, 10},
+		{EdgeWrap, 30},
+		{EdgeMirror, 10},
+		{EdgeConstant, 99},
+	}
+	for _, tt := range tests {
+		pgm := newTestPGM(3, 1, func(x, y int) uint16 { return uint16(10 * (x + 1)) })
+		pgm.Convolve(k, ConvolveOptions{Edge: tt.edge, Constant: 99})
+		if got := pgm.data[0][0]; got != tt.want {
+			t.Errorf("edge %d: got %d, want %d", tt.edge, got, tt.want)
+		}
+	}
+}
+
+func TestSeparableMatchesFullKernel(t *testing.T) {
+	separable := GaussianKernel(1)
+	full, err := NewKernel(separable.Width, separable.Height, separable.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(separable.Sum()-1) > 1e-9 {
+		t.Fatalf("Gaussian kernel sums to %g", separable.Sum())
+	}
+	fill := func(x, y int) uint16 { return uint16((x*37 ^ y*91) & 255) }
+	a, b := newTestPGM(9, 7, fill), newTestPGM(9, 7, fill)
+	a.Convolve(separable, ConvolveOptions{Edge: EdgeMirror})
+	b.Convolve(full, ConvolveOptions{Edge: EdgeMirror})
+	if !reflect.DeepEqual(a.data, b.data) {
+		t.Fatalf("separable %v\nfull %v", a.data, b.data)
+	}
+}
+
+func TestConvolveNormalizeAndBias(t *testing.T) {
+	k, _ := NewKernel(3, 1, []float64{1, 2, 1})
+	pgm := newTestPGM(3, 1, func(x, y int) uint16 { return 40 })
+	pgm.Convolve(k, ConvolveOptions{Normalize: true, Bias: 5})
+	if got := pgm.data[0][1]; got != 45 {
+		t.Fatalf("got %d, want 45", got)
+	}
+	if _, err := NewKernel(2, 2, []float64{1}); err == nil {
+		t.Fatal("kernel with missing weights accepted")
+	}
+}
+
+func TestSubImageConvolve(t *testing.T) {
+	pgm := newTestPGM(6, 6, func(x, y int) uint16 { return 0 })
+	pgm.data[1][1] = 90
+	view := pgm.SubImage(Rect(0, 0, 3, 3))
+	view.BoxBlur(1)
+	if pgm.data[1][1] != 10 || pgm.data[0][0] != 10 {
+		t.Fatalf("blur did not reach the parent: %v", pgm.data[:3])
+	}
+	view.Invert()
+	if pgm.data[1][1] != 245 {
+		t.Fatalf("view detached from the parent after blur: %d", pgm.data[1][1])
+	}
+}
+
+func TestSubImagePPMConvolve(t *testing.T) {
+	ppm := newTestPPM(4, 4, func(x, y int) Pixel { return Pixel{} })
+	ppm.data[1][1] = Pixel{90, 0, 180}
+	view := ppm.SubImage(Rect(0, 0, 3, 3))
+	view.BoxBlur(1)
+	if got := ppm.data[0][0]; got != (Pixel{10, 0, 20}) {
+		t.Fatalf("blur did not reach the parent: %v", got)
+	}
+}
@@ -0,0 +1,183 @@
+package Netpbm
+
+import "fmt"
+
+// Gravity tells where an image is anchored when its canvas is resized.
+type Gravity int
+
+const (
+	GravityNorthWest Gravity = iota
+	GravityNorth
+	GravityNorthEast
+	GravityWest
+	GravityCenter
+	GravityEast
+	GravitySouthWest
+	GravitySouth
+	GravitySouthEast
+)
+
+// offset returns where the top-left corner of a width x height image lands on
+// a newWidth x newHeight canvas.
+func (g Gravity) offset(width, height, newWidth, newHeight int) (int, int) {
+	var x, y int
+	switch g {
+	case GravityNorth, GravityCenter, GravitySouth:
+		x = (newWidth - width) / 2
+	case GravityNorthEast, GravityEast, GravitySouthEast:
+		x = newWidth - width
+	}
+	switch g {
+	case GravityWest, GravityCenter, GravityEast:
+		y = (newHeight - height) / 2
+	case GravitySouthWest, GravitySouth, GravitySouthEast:
+		y = newHeight - height
+	}
+	return x, y
+}
+
+func checkCrop(x, y, w, h, width, height int) error {
+	if w <= 0 || h <= 0 || x < 0 || y < 0 || x+w > width || y+h > height {
+		return fmt.Errorf("crop rectangle %dx%d+%d+%d outside of a %dx%d image", w, h, x, y, width, height)
+	}
+	return nil
+}
+
+func checkPadding(top, right, bottom, left int) error {
+	if top < 0 || right < 0 || bottom < 0 || left < 0 {
+		return fmt.Errorf("padding cannot be negative")
+	}
+	return nil
+}
+
+// Crop keeps the w x h region whose top-left corner is (x, y).
+func (pbm *PBM) Crop(x, y, w, h int) error {
+	if err := checkCrop(x, y, w, h, pbm.width, pbm.height); err != nil {
+		return err
+	}
+	pbm.data = storeGrid(pbm.data, placeGrid(pbm.data, pbm.width, pbm.height, w, h, -x, -y, false))
+	pbm.width, pbm.height = w, h
+	return nil
+}
+
+// Pad adds borders of the given widths around the image.
+func (pbm *PBM) Pad(top, right, bottom, left int, fill bool) error {
+	if err := checkPadding(top, right, bottom, left); err != nil {
+		return err
+	}
+	w, h := pbm.width+left+right, pbm.height+top+bottom
+	pbm.data = storeGrid(pbm.data, placeGrid(pbm.data, pbm.width, pbm.height, w, h, left, top, fill))
+	pbm.width, pbm.height = w, h
+	return nil
+}
+
+// ResizeCanvas changes the image size without scaling, anchoring the content
+// according to gravity. New areas are set to fill, overflowing parts are cut.
+func (pbm *PBM) ResizeCanvas(w, h int, gravity Gravity, fill bool) error {
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("invalid canvas size: %dx%d", w, h)
+	}
+	x, y := gravity.offset(pbm.width, pbm.height, w, h)
+	pbm.data = storeGrid(pbm.data, placeGrid(pbm.data, pbm.width, pbm.height, w, h, x, y, fill))
+	pbm.width, pbm.height = w, h
+	return nil
+}
+
+// AutoCrop trims the borders having the color of the top-left pixel, like
+// pnmcrop. A blank image, where every pixel has that color, has nothing to
+// keep: it is left unchanged and no error is returned.
+func (pbm *PBM) AutoCrop() error {
+	x0, y0, x1, y1, ok := autoCropBounds(pbm.data, pbm.width, pbm.height)
+	if !ok {
+		return nil
+	}
+	return pbm.Crop(x0, y0, x1-x0, y1-y0)
+}
+
+// Crop keeps the w x h region whose top-left corner is (x, y).
+func (pgm *PGM) Crop(x, y, w, h int) error {
+	if err := checkCrop(x, y, w, h, pgm.width, pgm.height); err != nil {
+		return err
+	}
+	pgm.data = storeGrid(pgm.data, placeGrid(pgm.data, pgm.width, pgm.height, w, h, -x, -y, 0))
+	pgm.width, pgm.height = w, h
+	return nil
+}
+
+// Pad adds borders of the given widths around the image.
+func (pgm *PGM) Pad(top, right, bottom, left int, fill uint16) error {
+	if err := checkPadding(top, right, bottom, left); err != nil {
+		return err
+	}
+	w, h := pgm.width+left+right, pgm.height+top+bottom
+	pgm.data = storeGrid(pgm.data, placeGrid(pgm.data, pgm.width, pgm.height, w, h, left, top, fill))
+	pgm.width, pgm.height = w, h
+	return nil
+}
+
+// ResizeCanvas changes the image size without scaling, anchoring the content
+// according to gravity. New areas are set to fill, overflowing parts are cut.
+func (pgm *PGM) ResizeCanvas(w, h int, gravity Gravity, fill uint16) error {
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("invalid canvas size: %dx%d", w, h)
+	}
+	x, y := gravity.offset(pgm.width, pgm.height, w, h)
+	pgm.data = storeGrid(pgm.data, placeGrid(pgm.data, pgm.width, pgm.height, w, h, x, y, fill))
+	pgm.width, pgm.height = w, h
+	return nil
+}
+
+// AutoCrop trims the borders having the value of the top-left pixel, like
+// pnmcrop. A blank image, where every pixel has that value, has nothing to
+// keep: it is left unchanged and no error is returned.
+func (pgm *PGM) AutoCrop() error {
+	x0, y0, x1, y1, ok := autoCropBounds(pgm.data, pgm.width, pgm.height)
+	if !ok {
+		return nil
+	}
+	return pgm.Crop(x0, y0, x1-x0, y1-y0)
+}
+
+// Crop keeps the w x h region whose top-left corner is (x, y).
+func (ppm *PPM) Crop(x, y, w, h int) error {
+	if err := checkCrop(x, y, w, h, ppm.width, ppm.height); err != nil {
+		return err
+	}
+	ppm.data = storeGrid(ppm.data, placeGrid(ppm.data, ppm.width, ppm.height, w, h, -x, -y, Pixel{}))
+	ppm.width, ppm.height = w, h
+	return nil
+}
+
+// Pad adds borders of the given widths around the image.
+func (ppm *PPM) Pad(top, right, bottom, left int, fill Pixel) error {
+	if err := checkPadding(top, right, bottom, left); err != nil {
+		return err
+	}
+	w, h := ppm.width+left+right, ppm.height+top+bottom
+	ppm.data = storeGrid(ppm.data, placeGrid(ppm.data, ppm.width, ppm.height, w, h, left, top, fill))
+	ppm.width, ppm.height = w, h
+	return nil
+}
+
+// ResizeCanvas changes the image size without scaling, anchoring the content
+// according to gravity. New areas are set to fill, overflowing parts are cut.
+func (ppm *PPM) ResizeCanvas(w, h int, gravity Gravity, fill Pixel) error {
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("invalid canvas size: %dx%d", w, h)
+	}
+	x, y := gravity.offset(ppm.width, ppm.height, w, h)
+	ppm.data = storeGrid(ppm.data, placeGrid(ppm.data, ppm.width, ppm.height, w, h, x, y, fill))
+	ppm.width, ppm.height = w, h
+	return nil
+}
+
+// AutoCrop trims the borders having the color of the top-left pixel, like
+// pnmcrop. A blank image, where every pixel has that color, has nothing to
+// keep: it is left unchanged and no error is returned.
+func (ppm *PPM) AutoCrop() error {
+	x0, y0, x1, y1, ok := autoCropBounds(ppm.data, ppm.width, ppm.height)
+	if !ok {
+		return nil
+	}
+	return ppm.Crop(x0, y0, x1-x0, y1-y0)
+}
@@ -0,0 +1,83 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	pgm := newTestPGM(4, 3, func(x, y int) uint16 { return uint16(10*y + x) })
+	if err := pgm.Crop(1, 1, 2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]uint16{{11, 12}, {21, 22}}; !reflect.DeepEqual(pgm.data, want) {
+		t.Fatalf("got %v, want %v", pgm.data, want)
+	}
+	for _, r := range [][4]int{{-1, 0, 1, 1}, {0, 0, 3, 1}, {0, 0, 0, 1}, {1, 1, 2, 1}} {
+		if err := pgm.Crop(r[0], r[1], r[2], r[3]); err == nil {
+			t.Errorf("Crop%v accepted on a 2x2 image", r)
+		}
+	}
+}
+
+func TestPadAndResizeCanvas(t *testing.T) {
+	pbm := newTestPBM("#")
+	if err := pbm.Pad(1, 2, 0, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"....", ".#.."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("Pad: got %v, want %v", pbmRows(pbm), want)
+	}
+	if err := pbm.Pad(-1, 0, 0, 0, false); err == nil {
+		t.Error("negative padding accepted")
+	}
+
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{uint16(x), uint16(y), 9} })
+	fill := Pixel{7, 7, 7}
+	if err := ppm.ResizeCanvas(4, 3, GravitySouthEast, fill); err != nil {
+		t.Fatal(err)
+	}
+	if ppm.data[0][0] != fill || ppm.data[1][2] != (Pixel{0, 0, 9}) || ppm.data[2][3] != (Pixel{1, 1, 9}) {
+		t.Fatalf("got %v", ppm.data)
+	}
+
+	square := newTestPPM(3, 3, func(x, y int) Pixel { return Pixel{uint16(x), uint16(y), 9} })
+	if err := square.ResizeCanvas(1, 1, GravityCenter, fill); err != nil {
+		t.Fatal(err)
+	}
+	if square.data[0][0] != (Pixel{1, 1, 9}) {
+		t.Fatalf("shrinking around the center: got %v", square.data[0][0])
+	}
+	if err := ppm.ResizeCanvas(0, 1, GravityCenter, fill); err == nil {
+		t.Error("empty canvas accepted")
+	}
+}
+
+func TestAutoCrop(t *testing.T) {
+	pbm := newTestPBM(
+		".....",
+		"..#..",
+		".#...",
+		".....",
+	)
+	if err := pbm.AutoCrop(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".#", "#."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("got %v, want %v", pbmRows(pbm), want)
+	}
+}
+
+func TestAutoCropBlankImage(t *testing.T) {
+	pgm := newTestPGM(3, 2, func(x, y int) uint16 { return 80 })
+	if err := pgm.AutoCrop(); err != nil {
+		t.Fatalf("blank image: %v", err)
+	}
+	if pgm.width != 3 || pgm.height != 2 || pgm.data[1][2] != 80 {
+		t.Fatalf("blank image changed: %dx%d %v", pgm.width, pgm.height, pgm.data)
+	}
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{} })
+	if err := ppm.AutoCrop(); err != nil || ppm.width != 2 || ppm.height != 2 {
+		t.Fatalf("blank image: %v, size %dx%d", err, ppm.width, ppm.height)
+	}
+}
@@ -0,0 +1,296 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// WindowShape is the shape of the neighbourhood used by rank filters.
+type WindowShape int
+
+const (
+	// WindowSquare covers a (2r+1) x (2r+1) square.
+	WindowSquare WindowShape = iota
+	// WindowCross covers the row and column through the center.
+	WindowCross
+	// WindowDisk covers the pixels within distance r of the center.
+	WindowDisk
+)
+
+// windowOffsets lists the offsets covered by a window of the given radius.
+func windowOffsets(radius int, shape WindowShape) []Point {
+	var offsets []Point
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			switch shape {
+			case WindowCross:
+				if dx != 0 && dy != 0 {
+					continue
+				}
+			case WindowDisk:
+				if dx*dx+dy*dy > radius*radius {
+					continue
+				}
+			}
+			offsets = append(offsets, Point{dx, dy})
+		}
+	}
+	return offsets
+}
+
+// rankPlane replaces each sample by the value found at the given percentile
+// of its sorted neighbourhood. Borders are extended by repetition.
+func rankPlane(data [][]uint16, width, height int, offsets []Point, percentile float64) [][]uint16 {
+	out := newGrid[uint16](width, height)
+	window := make([]int, len(offsets))
+	k := int(math.Round(percentile / 100 * float64(len(offsets)-1)))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for i, o := range offsets {
+				window[i] = int(data[clamp(y+o.Y, 0, height-1)][clamp(x+o.X, 0, width-1)])
+			}
+			sort.Ints(window)
+			out[y][x] = uint16(window[k])
+		}
+	}
+	return out
+}
+
+func checkRank(radius int, percentile float64) error {
+	if radius < 0 {
+		return fmt.Errorf("invalid radius: %d", radius)
+	}
+	if percentile < 0 || percentile > 100 {
+		return fmt.Errorf("percentile must be between 0 and 100: %g", percentile)
+	}
+	return nil
+}
+
+// PercentileFilter replaces each pixel by the given percentile, in [0, 100],
+// of the values in its neighbourhood.
+func (pgm *PGM) PercentileFilter(radius int, shape WindowShape, percentile float64) error {
+	if err := checkRank(radius, percentile); err != nil {
+		return err
+	}
+	pgm.data = storeGrid(pgm.data, rankPlane(pgm.data, pgm.width, pgm.height, windowOffsets(radius, shape), percentile))
+	return nil
+}
+
+// Median replaces each pixel by the median of its neighbourhood. It removes
+// salt-and-pepper noise while keeping edges sharp.
+func (pgm *PGM) Median(radius int, shape WindowShape) error {
+	return pgm.PercentileFilter(radius, shape, 50)
+}
+
+// MinFilter replaces each pixel by the smallest value of its neighbourhood.
+func (pgm *PGM) MinFilter(radius int, shape WindowShape) error {
+	return pgm.PercentileFilter(radius, shape, 0)
+}
+
+// MaxFilter replaces each pixel by the largest value of its neighbourhood.
+func (pgm *PGM) MaxFilter(radius int, shape WindowShape) error {
+	return pgm.PercentileFilter(radius, shape, 100)
+}
+
+// PercentileFilter applies PGM.PercentileFilter to each channel.
+func (ppm *PPM) PercentileFilter(radius int, shape WindowShape, percentile float64) error {
+	if err := checkRank(radius, percentile); err != nil {
+		return err
+	}
+	offsets := windowOffsets(radius, shape)
+	ppm.mapChannels(ppm.width, ppm.height, func(c Channel, plane [][]uint16) [][]uint16 {
+		return rankPlane(plane, ppm.width, ppm.height, offsets, percentile)
+	})
+	return nil
+}
+
+// Median replaces each sample by the median of its neighbourhood, channel by
+// channel.
+func (ppm *PPM) Median(radius int, shape WindowShape) error {
+	return ppm.PercentileFilter(radius, shape, 50)
+}
+
+// MinFilter replaces each sample by the smallest value of its neighbourhood.
+func (ppm *PPM) MinFilter(radius int, shape WindowShape) error {
+	return ppm.PercentileFilter(radius, shape, 0)
+}
+
+// MaxFilter replaces each sample by the largest value of its neighbourhood.
+func (ppm *PPM) MaxFilter(radius int, shape WindowShape) error {
+	return ppm.PercentileFilter(radius, shape, 100)
+}
+
+// bilateralPlanes filters planes together: the range weight uses the color
+// distance over all planes, so channels stay aligned.
+func bilateralPlanes(planes [][][]uint16, width, height int, sigmaSpatial, sigmaRange float64) [][][]float64 {
+	radius := int(math.Ceil(2 * sigmaSpatial))
+	spatial := make([]float64, (2*radius+1)*(2*radius+1))
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*(2*radius+1)+dx+radius] = math.Exp(-float64(dx*dx+dy*dy) / (2 * sigmaSpatial * sigmaSpatial))
+		}
+	}
+
+	out := make([][][]float64, len(planes))
+	for c := range out {
+		out[c] = newGrid[float64](width, height)
+	}
+	sums := make([]float64, len(planes))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for c := range sums {
+				sums[c] = 0
+			}
+			total := 0.0
+			for dy := -radius; dy <= radius; dy++ {
+				ny := y + dy
+				if ny < 0 || ny >= height {
+					continue
+				}
+				for dx := -radius; dx <= radius; dx++ {
+					nx := x + dx
+					if nx < 0 || nx >= width {
+						continue
+					}
+					dist := 0.0
+					for _, plane := range planes {
+						d := float64(plane[ny][nx]) - float64(plane[y][x])
+						dist += d * d
+					}
+					w := spatial[(dy+radius)*(2*radius+1)+dx+radius] * math.Exp(-dist/(2*sigmaRange*sigmaRange))
+					for c, plane := range planes {
+						sums[c] += w * float64(plane[ny][nx])
+					}
+					total += w
+				}
+			}
+			for c := range out {
+				out[c][y][x] = sums[c] / total
+			}
+		}
+	}
+	return out
+}
+
+func checkBilateral(sigmaSpatial, sigmaRange float64) error {
+	if sigmaSpatial <= 0 || sigmaRange <= 0 {
+		return fmt.Errorf("sigmas must be positive")
+	}
+	return nil
+}
+
+// Bilateral smooths the image while preserving edges: neighbours are weighted
+// by their distance (sigmaSpatial, in pixels) and by how close their value is
+// (sigmaRange, in sample units).
+func (pgm *PGM) Bilateral(sigmaSpatial, sigmaRange float64) error {
+	if err := checkBilateral(sigmaSpatial, sigmaRange); err != nil {
+		return err
+	}
+	out := bilateralPlanes([][][]uint16{pgm.data}, pgm.width, pgm.height, sigmaSpatial, sigmaRange)
+	pgm.data = storeGrid(pgm.data, fromFloatPlane(out[0], pgm.width, pgm.height, pgm.max))
+	return nil
+}
+
+// Bilateral smooths the image while preserving edges. The range weight uses
+// the distance between whole colors.
+func (ppm *PPM) Bilateral(sigmaSpatial, sigmaRange float64) error {
+	if err := checkBilateral(sigmaSpatial, sigmaRange); err != nil {
+		return err
+	}
+	r, g, b := ppm.Channels()
+	out := bilateralPlanes([][][]uint16{r.data, g.data, b.data}, ppm.width, ppm.height, sigmaSpatial, sigmaRange)
+	ppm.mapChannels(ppm.width, ppm.height, func(c Channel, plane [][]uint16) [][]uint16 {
+		return fromFloatPlane(out[c], ppm.width, ppm.height, ppm.max)
+	})
+	return nil
+}
+
+// nonLocalMeansPlanes denoises planes together by averaging pixels whose
+// surrounding patches look alike, within a search window.
+func nonLocalMeansPlanes(planes [][][]uint16, width, height int, h float64, patchRadius, searchRadius int) [][][]float64 {
+	patchSize := float64((2*patchRadius + 1) * (2*patchRadius + 1) * len(planes))
+	at := func(plane [][]uint16, x, y int) float64 {
+		return float64(plane[clamp(y, 0, height-1)][clamp(x, 0, width-1)])
+	}
+
+	out := make([][][]float64, len(planes))
+	for c := range out {
+		out[c] = newGrid[float64](width, height)
+	}
+	sums := make([]float64, len(planes))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			for c := range sums {
+				sums[c] = 0
+			}
+			total := 0.0
+			for sy := y - searchRadius; sy <= y+searchRadius; sy++ {
+				if sy < 0 || sy >= height {
+					continue
+				}
+				for sx := x - searchRadius; sx <= x+searchRadius; sx++ {
+					if sx < 0 || sx >= width {
+						continue
+					}
+					// Squared distance between the two patches
+					dist := 0.0
+					for _, plane := range planes {
+						for py := -patchRadius; py <= patchRadius; py++ {
+							for px := -patchRadius; px <= patchRadius; px++ {
+								d := at(plane, x+px, y+py) - at(plane, sx+px, sy+py)
+								dist += d * d
+							}
+						}
+					}
+					w := math.Exp(-dist / patchSize / (h * h))
+					for c, plane := range planes {
+						sums[c] += w * float64(plane[sy][sx])
+					}
+					total += w
+				}
+			}
+			for c := range out {
+				out[c][y][x] = sums[c] / total
+			}
+		}
+	}
+	return out
+}
+
+func checkNonLocalMeans(h float64, patchRadius, searchRadius int) error {
+	if h <= 0 {
+		return fmt.Errorf("filtering strength must be positive")
+	}
+	if patchRadius < 0 || searchRadius < 0 {
+		return fmt.Errorf("invalid radius")
+	}
+	return nil
+}
+
+// NonLocalMeans denoises the image by averaging, within searchRadius, the
+// pixels whose (2*patchRadius+1) square patch resembles the one around the
+// pixel. h, in sample units, sets the filtering strength and should be close
+// to the noise standard deviation. Typical values are 1 and 7 for the radii.
+func (pgm *PGM) NonLocalMeans(h float64, patchRadius, searchRadius int) error {
+	if err := checkNonLocalMeans(h, patchRadius, searchRadius); err != nil {
+		return err
+	}
+	out := nonLocalMeansPlanes([][][]uint16{pgm.data}, pgm.width, pgm.height, h, patchRadius, searchRadius)
+	pgm.data = storeGrid(pgm.data, fromFloatPlane(out[0], pgm.width, pgm.height, pgm.max))
+	return nil
+}
+
+// NonLocalMeans denoises the image by averaging pixels with similar
+// surrounding patches, comparing whole colors. See PGM.NonLocalMeans.
+func (ppm *PPM) NonLocalMeans(h float64, patchRadius, searchRadius int) error {
+	if err := checkNonLocalMeans(h, patchRadius, searchRadius); err != nil {
+		return err
+	}
+	r, g, b := ppm.Channels()
+	out := nonLocalMeansPlanes([][][]uint16{r.data, g.data, b.data}, ppm.width, ppm.height, h, patchRadius, searchRadius)
+	ppm.mapChannels(ppm.width, ppm.height, func(c Channel, plane [][]uint16) [][]uint16 {
+		return fromFloatPlane(out[c], ppm.width, ppm.height, ppm.max)
+	})
+	return nil
+}
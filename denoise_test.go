@@ -0,0 +1,141 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWindowOffsets(t *testing.T) {
+	for _, tt := range []struct {
+		shape WindowShape
+		want  int
+	}{{WindowSquare, 25}, {WindowCross, 9}, {WindowDisk, 13}} {
+		if got := len(windowOffsets(2, tt.shape)); got != tt.want {
+			t.Errorf("shape %d: %d offsets, want %d", tt.shape, got, tt.want)
+		}
+	}
+}
+
+func TestMedianRemovesSaltAndPepper(t *testing.T) {
+	pgm := newTestPGM(5, 5, func(x, y int) uint16 { return 100 })
+	pgm.data[1][1] = 255
+	pgm.data[3][2] = 0
+	if err := pgm.Median(1, WindowSquare); err != nil {
+		t.Fatal(err)
+	}
+	for y := range pgm.data {
+		for x, v := range pgm.data[y] {
+			if v != 100 {
+				t.Fatalf("(%d, %d) = %d, want 100", x, y, v)
+			}
+		}
+	}
+}
+
+func TestMinMaxFilters(t *testing.T) {
+	fill := func(x, y int) uint16 { return uint16(10 * x) }
+	minimum := newTestPGM(4, 1, fill)
+	if err := minimum.MinFilter(1, WindowCross); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{0, 0, 10, 20}; !reflect.DeepEqual(minimum.data[0], want) {
+		t.Errorf("min: got %v, want %v", minimum.data[0], want)
+	}
+	maximum := newTestPGM(4, 1, fill)
+	if err := maximum.MaxFilter(1, WindowCross); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{10, 20, 30, 30}; !reflect.DeepEqual(maximum.data[0], want) {
+		t.Errorf("max: got %v, want %v", maximum.data[0], want)
+	}
+	if err := maximum.PercentileFilter(1, WindowSquare, 101); err == nil {
+		t.Error("percentile 101 accepted")
+	}
+	if err := maximum.Median(-1, WindowSquare); err == nil {
+		t.Error("negative radius accepted")
+	}
+}
+
+func TestPPMMedian(t *testing.T) {
+	ppm := newTestPPM(3, 3, func(x, y int) Pixel { return Pixel{10, 20, 30} })
+	ppm.data[1][1] = Pixel{255, 0, 255}
+	if err := ppm.Median(1, WindowSquare); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[1][1]; got != (Pixel{10, 20, 30}) {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestBilateralKeepsEdges(t *testing.T) {
+	step := func(x, y int) uint16 {
+		if x < 4 {
+			return 50
+		}
+		return 200
+	}
+	pgm := newTestPGM(8, 3, step)
+	if err := pgm.Bilateral(2, 10); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pgm.data, newTestPGM(8, 3, step).data) {
+		t.Fatalf("edge blurred: %v", pgm.data)
+	}
+	blurred := newTestPGM(8, 3, step)
+	blurred.GaussianBlur(2)
+	if blurred.data[1][3] == 50 {
+		t.Fatal("a Gaussian blur of the same size should blur the edge")
+	}
+	if err := pgm.Bilateral(0, 10); err == nil {
+		t.Fatal("zero sigma accepted")
+	}
+}
+
+// noisyPGM returns a flat image with deterministic noise of amplitude ±20.
+func noisyPGM() *PGM {
+	return newTestPGM(12, 12, func(x, y int) uint16 {
+		return uint16(100 + (x*7+y*13)%41 - 20)
+	})
+}
+
+func deviation(pgm *PGM, mean float64) float64 {
+	sum := 0.0
+	for y := range pgm.data {
+		for _, v := range pgm.data[y] {
+			d := float64(v) - mean
+			sum += d * d
+		}
+	}
+	return math.Sqrt(sum / float64(pgm.width*pgm.height))
+}
+
+func TestNonLocalMeansReducesNoise(t *testing.T) {
+	pgm := noisyPGM()
+	before := deviation(pgm, 100)
+	if err := pgm.NonLocalMeans(30, 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if after := deviation(pgm, 100); after > before/2 {
+		t.Fatalf("deviation %g -> %g", before, after)
+	}
+	if err := pgm.NonLocalMeans(0, 1, 5); err == nil {
+		t.Fatal("zero strength accepted")
+	}
+}
+
+func TestSubImageRankFilter(t *testing.T) {
+	pgm := newTestPGM(4, 4, func(x, y int) uint16 { return 0 })
+	pgm.data[0][0] = 200
+	view := pgm.SubImage(Rect(0, 0, 2, 2))
+	if err := view.Median(1, WindowSquare); err != nil {
+		t.Fatal(err)
+	}
+	if pgm.data[0][0] != 0 {
+		t.Fatalf("median did not reach the parent: %d", pgm.data[0][0])
+	}
+	view.Invert()
+	if pgm.data[0][0] != 255 || pgm.data[3][3] != 0 {
+		t.Fatalf("got %d and %d, want 255 and 0", pgm.data[0][0], pgm.data[3][3])
+	}
+}
@@ -0,0 +1,260 @@
+package Netpbm
+
+import "math"
+
+// DistanceMap holds a distance for every pixel, indexed [y][x]. Pixels with
+// no reachable feature hold +Inf.
+type DistanceMap struct {
+	Width, Height int
+	Values        [][]float64
+}
+
+// Max returns the largest finite distance of the map.
+func (d *DistanceMap) Max() float64 {
+	max := 0.0
+	for y := range d.Values {
+		for _, v := range d.Values[y] {
+			if !math.IsInf(v, 1) && v > max {
+				max = v
+			}
+		}
+	}
+	return max
+}
+
+// ToPGM renders the map as a PGM image with maxval 255, the largest finite
+// distance becoming white. Infinite distances are white too.
+func (d *DistanceMap) ToPGM() *PGM {
+	data := newGrid[uint16](d.Width, d.Height)
+	scale := 0.0
+	if max := d.Max(); max > 0 {
+		scale = 255 / max
+	}
+	for y := range data {
+		for x, v := range d.Values[y] {
+			if math.IsInf(v, 1) {
+				data[y][x] = 255
+			} else {
+				data[y][x] = uint16(clamp(int(math.Round(v*scale)), 0, 255))
+			}
+		}
+	}
+	return &PGM{data: data, width: d.Width, height: d.Height, magicNumber: "P2", max: 255}
+}
+
+// chamferDistance computes, for every pixel where inside is true, the chamfer
+// distance to the nearest pixel where it is false, counting straight steps
+// for horizontal or vertical moves and diagonal steps for diagonal ones. When
+// borderIsSource is true the pixels outside the image count as sources too.
+// Unreachable pixels keep a very large value.
+func chamferDistance(inside [][]bool, width, height, straight, diagonal int, borderIsSource bool) [][]int {
+	const inf = math.MaxInt32 / 2
+	d := newGrid[int](width, height)
+	at := func(x, y int) int {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			if borderIsSource {
+				return 0
+			}
+			return inf
+		}
+		return d[y][x]
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !inside[y][x] {
+				continue
+			}
+			v := inf
+			v = minInt(v, at(x-1, y)+straight)
+			v = minInt(v, at(x, y-1)+straight)
+			v = minInt(v, at(x-1, y-1)+diagonal)
+			v = minInt(v, at(x+1, y-1)+diagonal)
+			d[y][x] = minInt(v, inf)
+		}
+	}
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			if !inside[y][x] {
+				continue
+			}
+			v := d[y][x]
+			v = minInt(v, at(x+1, y)+straight)
+			v = minInt(v, at(x, y+1)+straight)
+			v = minInt(v, at(x+1, y+1)+diagonal)
+			v = minInt(v, at(x-1, y+1)+diagonal)
+			d[y][x] = minInt(v, inf)
+		}
+	}
+	return d
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// chamferMap runs chamferDistance towards the black pixels and converts the
+// result to a distance map, dividing by unit.
+func (pbm *PBM) chamferMap(straight, diagonal int, unit float64) *DistanceMap {
+	inside := newGrid[bool](pbm.width, pbm.height)
+	for y := range inside {
+		for x := range inside[y] {
+			inside[y][x] = !pbm.data[y][x]
+		}
+	}
+	d := chamferDistance(inside, pbm.width, pbm.height, straight, diagonal, false)
+	values := newGrid[float64](pbm.width, pbm.height)
+	for y := range values {
+		for x, v := range d[y] {
+			if v >= math.MaxInt32/2 {
+				values[y][x] = math.Inf(1)
+			} else {
+				values[y][x] = float64(v) / unit
+			}
+		}
+	}
+	return &DistanceMap{Width: pbm.width, Height: pbm.height, Values: values}
+}
+
+// ChamferDistanceTransform approximates the Euclidean distance from every
+// pixel to the nearest black pixel with a 3-4 chamfer mask, in pixels.
+func (pbm *PBM) ChamferDistanceTransform() *DistanceMap {
+	return pbm.chamferMap(3, 4, 3)
+}
+
+// ManhattanDistanceTransform computes the city-block distance from every
+// pixel to the nearest black pixel.
+func (pbm *PBM) ManhattanDistanceTransform() *DistanceMap {
+	return pbm.chamferMap(1, 2, 1)
+}
+
+// distance1D computes the squared Euclidean distance transform of a sampled
+// function with the lower envelope of parabolas (Felzenszwalb-Huttenlocher).
+// f holds 0 at features and +Inf elsewhere, or the result of a previous pass.
+// It returns the distances and, for each position, the index of the parabola
+// reaching the minimum (-1 when every value is infinite).
+func distance1D(f []float64) ([]float64, []int) {
+	n := len(f)
+	d := make([]float64, n)
+	index := make([]int, n)
+	v := make([]int, 0, n)
+	z := make([]float64, 0, n+1)
+
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], 1) {
+			continue
+		}
+		for len(v) > 0 {
+			p := v[len(v)-1]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s > z[len(z)-1] {
+				v = append(v, q)
+				z = append(z, s)
+				break
+			}
+			v = v[:len(v)-1]
+			z = z[:len(z)-1]
+		}
+		if len(v) == 0 {
+			v = append(v, q)
+			z = append(z[:0], math.Inf(-1))
+		}
+	}
+
+	if len(v) == 0 {
+		for q := range d {
+			d[q] = math.Inf(1)
+			index[q] = -1
+		}
+		return d, index
+	}
+	z = append(z, math.Inf(1))
+	k := 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := v[k]
+		d[q] = float64((q-p)*(q-p)) + f[p]
+		index[q] = p
+	}
+	return d, index
+}
+
+// euclidean computes the exact squared distance from every pixel to the
+// nearest black pixel and the coordinates of that pixel.
+func (pbm *PBM) euclidean() ([][]float64, [][]Point) {
+	w, h := pbm.width, pbm.height
+	columns := newGrid[float64](w, h)
+	nearestY := newGrid[int](w, h)
+	f := make([]float64, h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			if pbm.data[y][x] {
+				f[y] = 0
+			} else {
+				f[y] = math.Inf(1)
+			}
+		}
+		d, index := distance1D(f)
+		for y := 0; y < h; y++ {
+			columns[y][x] = d[y]
+			nearestY[y][x] = index[y]
+		}
+	}
+
+	squared := newGrid[float64](w, h)
+	nearest := newGrid[Point](w, h)
+	for y := 0; y < h; y++ {
+		d, index := distance1D(columns[y])
+		for x := 0; x < w; x++ {
+			squared[y][x] = d[x]
+			if index[x] < 0 {
+				nearest[y][x] = Point{-1, -1}
+			} else {
+				nearest[y][x] = Point{index[x], nearestY[y][index[x]]}
+			}
+		}
+	}
+	return squared, nearest
+}
+
+// DistanceTransform computes the exact Euclidean distance from every pixel to
+// the nearest black pixel, in linear time (Felzenszwalb-Huttenlocher). Use
+// Invert first to measure distances to the white pixels instead.
+func (pbm *PBM) DistanceTransform() *DistanceMap {
+	squared, _ := pbm.euclidean()
+	for y := range squared {
+		for x := range squared[y] {
+			squared[y][x] = math.Sqrt(squared[y][x])
+		}
+	}
+	return &DistanceMap{Width: pbm.width, Height: pbm.height, Values: squared}
+}
+
+// NearestFeature returns, for every pixel, the coordinates of the nearest
+// black pixel in Euclidean distance, indexed [y][x]. Pixels get (-1, -1) when
+// the image has no black pixel.
+func (pbm *PBM) NearestFeature() [][]Point {
+	_, nearest := pbm.euclidean()
+	return nearest
+}
+
+// Voronoi partitions the image between the connected components of black
+// pixels: every pixel gets the label of the component holding its nearest
+// black pixel. Labels match those of Label with the same connectivity.
+func (pbm *PBM) Voronoi(conn Connectivity) *LabelImage {
+	labels, _ := pbm.Label(conn)
+	nearest := pbm.NearestFeature()
+	out := &LabelImage{Width: pbm.width, Height: pbm.height, Labels: newGrid[int](pbm.width, pbm.height), Count: labels.Count}
+	for y := range nearest {
+		for x, p := range nearest[y] {
+			if p.X >= 0 {
+				out.Labels[y][x] = labels.Labels[p.Y][p.X]
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package Netpbm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteDistance is the reference Euclidean distance transform.
+func bruteDistance(pbm *PBM) [][]float64 {
+	out := newGrid[float64](pbm.width, pbm.height)
+	for y := range out {
+		for x := range out[y] {
+			best := math.Inf(1)
+			for fy := range pbm.data {
+				for fx, black := range pbm.data[fy] {
+					if black {
+						best = math.Min(best, math.Hypot(float64(x-fx), float64(y-fy)))
+					}
+				}
+			}
+			out[y][x] = best
+		}
+	}
+	return out
+}
+
+func TestDistanceTransformMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 20; i++ {
+		w, h := 5+rng.Intn(20), 5+rng.Intn(20)
+		pbm := &PBM{data: newGrid[bool](w, h), width: w, height: h, magicNumber: "P1"}
+		for y := range pbm.data {
+			for x := range pbm.data[y] {
+				pbm.data[y][x] = rng.Intn(100) < 4
+			}
+		}
+		want := bruteDistance(pbm)
+		got := pbm.DistanceTransform()
+		nearest := pbm.NearestFeature()
+		for y := range want {
+			for x := range want[y] {
+				if math.IsInf(want[y][x], 1) {
+					if !math.IsInf(got.Values[y][x], 1) || nearest[y][x] != (Point{-1, -1}) {
+						t.Fatalf("case %d: (%d, %d) should be unreachable", i, x, y)
+					}
+					continue
+				}
+				if math.Abs(got.Values[y][x]-want[y][x]) > 1e-9 {
+					t.Fatalf("case %d: distance at (%d, %d) = %g, want %g", i, x, y, got.Values[y][x], want[y][x])
+				}
+				p := nearest[y][x]
+				if !pbm.data[p.Y][p.X] || math.Abs(math.Hypot(float64(x-p.X), float64(y-p.Y))-want[y][x]) > 1e-9 {
+					t.Fatalf("case %d: nearest feature of (%d, %d) is %v", i, x, y, p)
+				}
+			}
+		}
+	}
+}
+
+func TestChamferAndManhattan(t *testing.T) {
+	pbm := newTestPBM(
+		".....",
+		".....",
+		"..#..",
+		".....",
+		".....",
+	)
+	manhattan := pbm.ManhattanDistanceTransform()
+	if manhattan.Values[0][0] != 4 || manhattan.Values[2][0] != 2 || manhattan.Values[2][2] != 0 {
+		t.Fatalf("Manhattan distances %v", manhattan.Values)
+	}
+	chamfer := pbm.ChamferDistanceTransform()
+	// Two diagonal steps of weight 4, in units of 3.
+	if math.Abs(chamfer.Values[0][0]-8.0/3) > 1e-9 || chamfer.Values[2][0] != 2 {
+		t.Fatalf("chamfer distances %v", chamfer.Values)
+	}
+	if chamfer.Max() != 8.0/3 {
+		t.Fatalf("Max = %g, want 8/3", chamfer.Max())
+	}
+}
+
+func TestDistanceMapWithoutFeatures(t *testing.T) {
+	pbm := newTestPBM("...", "...")
+	for _, d := range []*DistanceMap{pbm.DistanceTransform(), pbm.ChamferDistanceTransform(), pbm.ManhattanDistanceTransform()} {
+		if !math.IsInf(d.Values[1][2], 1) || d.Max() != 0 {
+			t.Fatalf("expected infinite distances, got %v", d.Values)
+		}
+		if pgm := d.ToPGM(); pgm.data[0][0] != 255 {
+			t.Fatalf("infinite distance rendered as %d", pgm.data[0][0])
+		}
+	}
+}
+
+func TestDistanceMapToPGM(t *testing.T) {
+	pbm := newTestPBM("#....")
+	pgm := pbm.ManhattanDistanceTransform().ToPGM()
+	want := []uint16{0, 64, 128, 191, 255}
+	for x, v := range want {
+		if pgm.data[0][x] != v {
+			t.Fatalf("got %v, want %v", pgm.data[0], want)
+		}
+	}
+}
+
+func TestVoronoi(t *testing.T) {
+	pbm := newTestPBM(
+		"#.....",
+		"......",
+		".....#",
+	)
+	v := pbm.Voronoi(Connectivity8)
+	if v.Count != 2 {
+		t.Fatalf("got %d regions, want 2", v.Count)
+	}
+	if v.Labels[0][1] != 1 || v.Labels[2][4] != 2 || v.Labels[0][0] != 1 || v.Labels[2][5] != 2 {
+		t.Fatalf("unexpected partition %v", v.Labels)
+	}
+}
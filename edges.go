@@ -0,0 +1,166 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// GradientOperator selects the pair of kernels used to estimate the image
+// gradient.
+type GradientOperator int
+
+const (
+	Sobel GradientOperator = iota
+	Scharr
+	Prewitt
+)
+
+// kernels returns the horizontal and vertical derivative kernels of the
+// operator, as separable kernels.
+func (op GradientOperator) kernels() (*Kernel, *Kernel) {
+	smooth := []float64{1, 2, 1}
+	switch op {
+	case Scharr:
+		smooth = []float64{3, 10, 3}
+	case Prewitt:
+		smooth = []float64{1, 1, 1}
+	}
+	derivative := []float64{-1, 0, 1}
+	return NewSeparableKernel(derivative, smooth), NewSeparableKernel(smooth, derivative)
+}
+
+// gradientPlanes returns the horizontal and vertical derivatives of a plane.
+func gradientPlanes(src [][]float64, width, height int, op GradientOperator) ([][]float64, [][]float64) {
+	kx, ky := op.kernels()
+	return convolvePlane(src, width, height, kx, EdgeClamp, 0), convolvePlane(src, width, height, ky, EdgeClamp, 0)
+}
+
+// Gradient computes the gradient of the image with the given operator. It
+// returns the magnitude, scaled so that the strongest edge reaches maxval, and
+// the direction, with angles from 0 to 360 degrees mapped to [0, maxval].
+func (pgm *PGM) Gradient(op GradientOperator) (*PGM, *PGM) {
+	gx, gy := gradientPlanes(toFloatPlane(pgm.data, pgm.width, pgm.height), pgm.width, pgm.height, op)
+	magnitude := newGrid[float64](pgm.width, pgm.height)
+	direction := newGrid[float64](pgm.width, pgm.height)
+	peak := 0.0
+	for y := range magnitude {
+		for x := range magnitude[y] {
+			m := math.Hypot(gx[y][x], gy[y][x])
+			magnitude[y][x] = m
+			peak = math.Max(peak, m)
+			angle := math.Atan2(gy[y][x], gx[y][x])
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			direction[y][x] = angle / (2 * math.Pi) * float64(pgm.max)
+		}
+	}
+	if peak > 0 {
+		for y := range magnitude {
+			for x := range magnitude[y] {
+				magnitude[y][x] *= float64(pgm.max) / peak
+			}
+		}
+	}
+	mag := &PGM{data: fromFloatPlane(magnitude, pgm.width, pgm.height, pgm.max), width: pgm.width, height: pgm.height, magicNumber: pgm.magicNumber, max: pgm.max}
+	dir := &PGM{data: fromFloatPlane(direction, pgm.width, pgm.height, pgm.max), width: pgm.width, height: pgm.height, magicNumber: pgm.magicNumber, max: pgm.max}
+	return mag, dir
+}
+
+// GradientField returns the raw horizontal and vertical derivatives of the
+// image, indexed [y][x], for callers that need unscaled values.
+func (pgm *PGM) GradientField(op GradientOperator) ([][]float64, [][]float64) {
+	return gradientPlanes(toFloatPlane(pgm.data, pgm.width, pgm.height), pgm.width, pgm.height, op)
+}
+
+// Canny detects edges with the Canny algorithm: Gaussian smoothing, Sobel
+// gradient, non-maximum suppression and hysteresis. low and high are the
+// hysteresis thresholds as fractions of the strongest gradient, in [0, 1].
+// Edge pixels are black (true) in the returned PBM image.
+func (pgm *PGM) Canny(sigma, low, high float64) (*PBM, error) {
+	if low < 0 || high > 1 || low > high {
+		return nil, fmt.Errorf("invalid thresholds: low %g, high %g", low, high)
+	}
+	w, h := pgm.width, pgm.height
+	src := toFloatPlane(pgm.data, w, h)
+	if sigma > 0 {
+		src = convolvePlane(src, w, h, GaussianKernel(sigma), EdgeClamp, 0)
+	}
+	gx, gy := gradientPlanes(src, w, h, Sobel)
+
+	magnitude := newGrid[float64](w, h)
+	peak := 0.0
+	for y := range magnitude {
+		for x := range magnitude[y] {
+			magnitude[y][x] = math.Hypot(gx[y][x], gy[y][x])
+			peak = math.Max(peak, magnitude[y][x])
+		}
+	}
+
+	// Non-maximum suppression along the gradient direction
+	thin := newGrid[float64](w, h)
+	at := func(x, y int) float64 {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0
+		}
+		return magnitude[y][x]
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m := magnitude[y][x]
+			if m == 0 {
+				continue
+			}
+			angle := math.Atan2(gy[y][x], gx[y][x]) * 180 / math.Pi
+			if angle < 0 {
+				angle += 180
+			}
+			var dx, dy int
+			switch {
+			case angle < 22.5 || angle >= 157.5:
+				dx, dy = 1, 0
+			case angle < 67.5:
+				dx, dy = 1, 1
+			case angle < 112.5:
+				dx, dy = 0, 1
+			default:
+				dx, dy = -1, 1
+			}
+			if m >= at(x+dx, y+dy) && m >= at(x-dx, y-dy) {
+				thin[y][x] = m
+			}
+		}
+	}
+
+	// Hysteresis: start from strong pixels and follow the connected weak ones
+	edges := &PBM{data: newGrid[bool](w, h), width: w, height: h, magicNumber: "P1"}
+	if peak == 0 {
+		return edges, nil
+	}
+	lowValue, highValue := low*peak, high*peak
+	var stack []Point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if thin[y][x] >= highValue && thin[y][x] > 0 && !edges.data[y][x] {
+				edges.data[y][x] = true
+				stack = append(stack, Point{x, y})
+				for len(stack) > 0 {
+					p := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					for ny := p.Y - 1; ny <= p.Y+1; ny++ {
+						for nx := p.X - 1; nx <= p.X+1; nx++ {
+							if nx < 0 || ny < 0 || nx >= w || ny >= h || edges.data[ny][nx] {
+								continue
+							}
+							if thin[ny][nx] >= lowValue && thin[ny][nx] > 0 {
+								edges.data[ny][nx] = true
+								stack = append(stack, Point{nx, ny})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return edges, nil
+}
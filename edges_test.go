@@ -0,0 +1,82 @@
+package Netpbm
+
+import "testing"
+
+func TestGradientVerticalStep(t *testing.T) {
+	for _, op := range []GradientOperator{Sobel, Scharr, Prewitt} {
+		pgm := newTestPGM(6, 4, func(x, y int) uint16 {
+			if x >= 3 {
+				return 200
+			}
+			return 0
+		})
+		mag, dir := pgm.Gradient(op)
+		if mag.data[1][2] != 255 || mag.data[1][3] != 255 {
+			t.Errorf("operator %d: magnitude at the step %d, %d", op, mag.data[1][2], mag.data[1][3])
+		}
+		if mag.data[1][0] != 0 || mag.data[1][5] != 0 {
+			t.Errorf("operator %d: magnitude on flat areas %d, %d", op, mag.data[1][0], mag.data[1][5])
+		}
+		if dir.data[1][2] != 0 {
+			t.Errorf("operator %d: direction %d, want 0 (pointing right)", op, dir.data[1][2])
+		}
+		gx, gy := pgm.GradientField(op)
+		if gx[1][2] <= 0 || gy[1][2] != 0 {
+			t.Errorf("operator %d: raw gradient (%g, %g)", op, gx[1][2], gy[1][2])
+		}
+	}
+}
+
+func TestCannySquare(t *testing.T) {
+	pgm := newTestPGM(20, 20, func(x, y int) uint16 {
+		if x >= 5 && x < 15 && y >= 5 && y < 15 {
+			return 255
+		}
+		return 0
+	})
+	edges, err := pgm.Canny(1, 0.2, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for y := range edges.data {
+		for x, black := range edges.data[y] {
+			if !black {
+				continue
+			}
+			count++
+			nearX := abs(x-5) <= 1 || abs(x-14) <= 1
+			nearY := abs(y-5) <= 1 || abs(y-14) <= 1
+			if !nearX && !nearY {
+				t.Fatalf("edge pixel (%d, %d) far from the square border", x, y)
+			}
+		}
+	}
+	// Each side of the square gives one line, one or two pixels thick.
+	if count < 30 || count > 90 {
+		t.Fatalf("%d edge pixels", count)
+	}
+	for _, p := range []Point{{4, 10}, {5, 10}, {14, 10}, {15, 10}} {
+		if !edges.data[p.Y][p.X] && !edges.data[p.Y][p.X+1] && !edges.data[p.Y][p.X-1] {
+			t.Errorf("no edge around %v", p)
+		}
+	}
+}
+
+func TestCannyFlatAndErrors(t *testing.T) {
+	pgm := newTestPGM(5, 5, func(x, y int) uint16 { return 77 })
+	edges, err := pgm.Canny(1, 0.1, 0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := range edges.data {
+		for x, black := range edges.data[y] {
+			if black {
+				t.Fatalf("edge on a flat image at (%d, %d)", x, y)
+			}
+		}
+	}
+	if _, err := pgm.Canny(1, 0.5, 0.2); err == nil {
+		t.Fatal("low threshold above high accepted")
+	}
+}
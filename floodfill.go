@@ -0,0 +1,153 @@
+package Netpbm
+
+// scanlineFill returns the region reachable from the seed through pixels for
+// which match is true. It fills whole horizontal spans and keeps pending spans
+// on an explicit stack, so it never recurses.
+func scanlineFill(width, height int, seed Point, conn Connectivity, match func(x, y int) bool) [][]bool {
+	region := newGrid[bool](width, height)
+	if seed.X < 0 || seed.Y < 0 || seed.X >= width || seed.Y >= height || !match(seed.X, seed.Y) {
+		return region
+	}
+	inside := func(x, y int) bool {
+		return !region[y][x] && match(x, y)
+	}
+
+	// With 8-connectivity, neighbouring rows are scanned one pixel further
+	extra := 0
+	if conn == Connectivity8 {
+		extra = 1
+	}
+
+	stack := []Point{seed}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !inside(p.X, p.Y) {
+			continue
+		}
+		left, right := p.X, p.X
+		for left > 0 && inside(left-1, p.Y) {
+			left--
+		}
+		for right < width-1 && inside(right+1, p.Y) {
+			right++
+		}
+		for x := left; x <= right; x++ {
+			region[p.Y][x] = true
+		}
+
+		for _, ny := range []int{p.Y - 1, p.Y + 1} {
+			if ny < 0 || ny >= height {
+				continue
+			}
+			from, to := clamp(left-extra, 0, width-1), clamp(right+extra, 0, width-1)
+			// Push a single seed per span found on the neighbouring row
+			inSpan := false
+			for x := from; x <= to; x++ {
+				if inside(x, ny) {
+					if !inSpan {
+						stack = append(stack, Point{x, ny})
+						inSpan = true
+					}
+				} else {
+					inSpan = false
+				}
+			}
+		}
+	}
+	return region
+}
+
+func regionToPBM(region [][]bool, width, height int) *PBM {
+	return &PBM{data: region, width: width, height: height, magicNumber: "P1"}
+}
+
+func pixelDistance(a, b Pixel) int {
+	d := abs(int(a.R) - int(b.R))
+	if g := abs(int(a.G) - int(b.G)); g > d {
+		d = g
+	}
+	if bl := abs(int(a.B) - int(b.B)); bl > d {
+		d = bl
+	}
+	return d
+}
+
+// Select returns, as a PBM mask, the region connected to the seed whose
+// colors differ from the seed color by at most tolerance on every channel.
+func (ppm *PPM) Select(seed Point, tolerance int, conn Connectivity) *PBM {
+	var target Pixel
+	if seed.X >= 0 && seed.Y >= 0 && seed.X < ppm.width && seed.Y < ppm.height {
+		target = ppm.data[seed.Y][seed.X]
+	}
+	region := scanlineFill(ppm.width, ppm.height, seed, conn, func(x, y int) bool {
+		return pixelDistance(ppm.data[y][x], target) <= tolerance
+	})
+	return regionToPBM(region, ppm.width, ppm.height)
+}
+
+// FloodFill paints with color the region connected to the seed whose colors
+// differ from the seed color by at most tolerance on every channel. It can
+// fill the closed shapes drawn with DrawRectangle, DrawTriangle or DrawCircle.
+func (ppm *PPM) FloodFill(seed Point, color Pixel, tolerance int, conn Connectivity) {
+	mask := ppm.Select(seed, tolerance, conn)
+	for y := range mask.data {
+		for x, in := range mask.data[y] {
+			if in {
+				ppm.data[y][x] = color
+			}
+		}
+	}
+}
+
+// Select returns, as a PBM mask, the region connected to the seed whose values
+// differ from the seed value by at most tolerance.
+func (pgm *PGM) Select(seed Point, tolerance int, conn Connectivity) *PBM {
+	var target uint16
+	if seed.X >= 0 && seed.Y >= 0 && seed.X < pgm.width && seed.Y < pgm.height {
+		target = pgm.data[seed.Y][seed.X]
+	}
+	region := scanlineFill(pgm.width, pgm.height, seed, conn, func(x, y int) bool {
+		return abs(int(pgm.data[y][x])-int(target)) <= tolerance
+	})
+	return regionToPBM(region, pgm.width, pgm.height)
+}
+
+// FloodFill sets to value the region connected to the seed whose values differ
+// from the seed value by at most tolerance.
+func (pgm *PGM) FloodFill(seed Point, value uint16, tolerance int, conn Connectivity) {
+	mask := pgm.Select(seed, tolerance, conn)
+	for y := range mask.data {
+		for x, in := range mask.data[y] {
+			if in {
+				pgm.data[y][x] = value
+			}
+		}
+	}
+}
+
+// Select returns, as a PBM mask, the region of pixels of the seed's color
+// connected to it.
+func (pbm *PBM) Select(seed Point, conn Connectivity) *PBM {
+	var target bool
+	if seed.X >= 0 && seed.Y >= 0 && seed.X < pbm.width && seed.Y < pbm.height {
+		target = pbm.data[seed.Y][seed.X]
+	}
+	region := scanlineFill(pbm.width, pbm.height, seed, conn, func(x, y int) bool {
+		return pbm.data[y][x] == target
+	})
+	return regionToPBM(region, pbm.width, pbm.height)
+}
+
+// FloodFill sets to value the region of pixels of the seed's color connected
+// to it.
+func (pbm *PBM) FloodFill(seed Point, value bool, conn Connectivity) {
+	mask := pbm.Select(seed, conn)
+	for y := range mask.data {
+		for x, in := range mask.data[y] {
+			if in {
+				pbm.data[y][x] = value
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package Netpbm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// naiveFill is a breadth-first reference for scanlineFill.
+func naiveFill(width, height int, seed Point, conn Connectivity, match func(x, y int) bool) [][]bool {
+	region := newGrid[bool](width, height)
+	if !match(seed.X, seed.Y) {
+		return region
+	}
+	region[seed.Y][seed.X] = true
+	queue := []Point{seed}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, n := range conn.offsets() {
+			x, y := p.X+n.X, p.Y+n.Y
+			if x < 0 || y < 0 || x >= width || y >= height || region[y][x] || !match(x, y) {
+				continue
+			}
+			region[y][x] = true
+			queue = append(queue, Point{x, y})
+		}
+	}
+	return region
+}
+
+func TestScanlineFillMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	const w, h = 23, 17
+	for i := 0; i < 50; i++ {
+		grid := newGrid[bool](w, h)
+		for y := range grid {
+			for x := range grid[y] {
+				grid[y][x] = rng.Intn(100) < 60
+			}
+		}
+		match := func(x, y int) bool { return grid[y][x] }
+		seed := Point{rng.Intn(w), rng.Intn(h)}
+		for _, conn := range []Connectivity{Connectivity4, Connectivity8} {
+			got := scanlineFill(w, h, seed, conn, match)
+			want := naiveFill(w, h, seed, conn, match)
+			for y := range want {
+				for x := range want[y] {
+					if got[y][x] != want[y][x] {
+						t.Fatalf("case %d, connectivity %v: mismatch at (%d, %d)", i, conn, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPBMFloodFill(t *testing.T) {
+	pbm := newTestPBM(
+		"#####.",
+		"#...#.",
+		"#...#.",
+		"#####.",
+	)
+	pbm.FloodFill(Point{2, 1}, true, Connectivity4)
+	for _, row := range pbmRows(pbm) {
+		if row != "#####." {
+			t.Fatalf("interior not filled: %v", pbmRows(pbm))
+		}
+	}
+
+	diagonal := newTestPBM(
+		"#.",
+		".#",
+	)
+	if n := diagonal.Select(Point{0, 0}, Connectivity4).bits().count(); n != 1 {
+		t.Fatalf("4-connectivity selected %d pixels, want 1", n)
+	}
+	if n := diagonal.Select(Point{0, 0}, Connectivity8).bits().count(); n != 2 {
+		t.Fatalf("8-connectivity selected %d pixels, want 2", n)
+	}
+}
+
+func TestPGMFloodFillTolerance(t *testing.T) {
+	pgm := newTestPGM(5, 1, func(x, y int) uint16 { return []uint16{10, 12, 14, 40, 11}[x] })
+	pgm.FloodFill(Point{0, 0}, 99, 4, Connectivity4)
+	want := []uint16{99, 99, 99, 40, 11}
+	for x, v := range want {
+		if pgm.data[0][x] != v {
+			t.Fatalf("got %v, want %v", pgm.data[0], want)
+		}
+	}
+}
+
+func TestPPMFloodFill(t *testing.T) {
+	red, blue := Pixel{200, 0, 0}, Pixel{0, 0, 200}
+	ppm := newTestPPM(4, 4, func(x, y int) Pixel {
+		if x == 2 {
+			return blue
+		}
+		return Pixel{200, uint16(y + 1), 0}
+	})
+	ppm.FloodFill(Point{0, 0}, red, 3, Connectivity8)
+	for y := 0; y < 4; y++ {
+		if ppm.data[y][0] != red || ppm.data[y][1] != red || ppm.data[y][2] != blue {
+			t.Fatalf("row %d: %v", y, ppm.data[y])
+		}
+		if ppm.data[y][3] == red {
+			t.Fatalf("fill crossed the blue column at row %d", y)
+		}
+	}
+}
+
+func TestSelectOutOfBounds(t *testing.T) {
+	pgm := newTestPGM(3, 3, func(x, y int) uint16 { return 0 })
+	if n := pgm.Select(Point{-1, 5}, 255, Connectivity8).bits().count(); n != 0 {
+		t.Fatalf("selected %d pixels from an outside seed", n)
+	}
+}
@@ -0,0 +1,184 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// ImageHash is a 64-bit perceptual hash. Similar images get hashes with a
+// small Hamming distance.
+type ImageHash uint64
+
+// Distance returns the number of bits that differ between two hashes.
+func (h ImageHash) Distance(other ImageHash) int {
+	return bits.OnesCount64(uint64(h ^ other))
+}
+
+// HammingDistance returns the number of bits that differ between a and b.
+func HammingDistance(a, b ImageHash) int {
+	return a.Distance(b)
+}
+
+// Similar reports whether two hashes differ by at most maxDistance bits.
+// A threshold around 10 suits near-duplicate detection.
+func (h ImageHash) Similar(other ImageHash, maxDistance int) bool {
+	return h.Distance(other) <= maxDistance
+}
+
+// String returns the hash as 16 hexadecimal digits.
+func (h ImageHash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// hashBits sets bit i, from the most significant one, for every value
+// greater than threshold.
+func hashBits(values []float64, threshold float64) ImageHash {
+	var h ImageHash
+	for _, v := range values {
+		h <<= 1
+		if v > threshold {
+			h |= 1
+		}
+	}
+	return h
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// hashPlane shrinks the image with a box filter, the usual first step of
+// every hash. It returns nil for an empty image.
+func (pgm *PGM) hashPlane(width, height int) [][]float64 {
+	if pgm.width == 0 || pgm.height == 0 {
+		return nil
+	}
+	return resampleFloat(pgm.data, pgm.width, pgm.height, width, height, FilterBox)
+}
+
+// AverageHash computes the average hash: the image is shrunk to 8x8 and
+// each bit tells whether a pixel is brighter than the mean. An empty image
+// hashes to 0.
+func (pgm *PGM) AverageHash() ImageHash {
+	plane := pgm.hashPlane(8, 8)
+	if plane == nil {
+		return 0
+	}
+	values := make([]float64, 0, 64)
+	sum := 0.0
+	for _, row := range plane {
+		for _, v := range row {
+			values = append(values, v)
+			sum += v
+		}
+	}
+	return hashBits(values, sum/64)
+}
+
+// DifferenceHash computes the difference hash: the image is shrunk to 9x8
+// and each bit tells whether a pixel is brighter than its right neighbour.
+// An empty image hashes to 0.
+func (pgm *PGM) DifferenceHash() ImageHash {
+	plane := pgm.hashPlane(9, 8)
+	if plane == nil {
+		return 0
+	}
+	values := make([]float64, 0, 64)
+	for _, row := range plane {
+		for x := 0; x < 8; x++ {
+			values = append(values, row[x]-row[x+1])
+		}
+	}
+	return hashBits(values, 0)
+}
+
+// PerceptualHash computes the DCT hash: the image is shrunk to 32x32 and
+// each bit tells whether one of the DCT coefficients of frequencies 1 to 8 in
+// both directions is above their median. The DC coefficient, which only
+// carries the mean brightness, is left out. An empty image hashes to 0.
+func (pgm *PGM) PerceptualHash() ImageHash {
+	const size, low = 32, 9
+	plane := pgm.hashPlane(size, size)
+	if plane == nil {
+		return 0
+	}
+
+	// Separable DCT-II, limited to the low frequencies
+	cos := newGrid[float64](size, low)
+	for k := 0; k < low; k++ {
+		for n := 0; n < size; n++ {
+			cos[k][n] = math.Cos(math.Pi / size * (float64(n) + 0.5) * float64(k))
+		}
+	}
+	rows := newGrid[float64](low, size)
+	for y := 0; y < size; y++ {
+		for k := 0; k < low; k++ {
+			sum := 0.0
+			for n := 0; n < size; n++ {
+				sum += plane[y][n] * cos[k][n]
+			}
+			rows[y][k] = sum
+		}
+	}
+	// The zero frequency row and column are skipped
+	values := make([]float64, 0, 64)
+	for k := 1; k < low; k++ {
+		for u := 1; u < low; u++ {
+			sum := 0.0
+			for n := 0; n < size; n++ {
+				sum += rows[n][u] * cos[k][n]
+			}
+			values = append(values, sum)
+		}
+	}
+	return hashBits(values, median(values))
+}
+
+// WaveletHash computes the Haar wavelet hash: the image is shrunk to 64x64,
+// decomposed over three Haar levels, and each bit tells whether one of the
+// 8x8 approximation coefficients is above their median. An empty image
+// hashes to 0.
+func (pgm *PGM) WaveletHash() ImageHash {
+	size := 64
+	plane := pgm.hashPlane(size, size)
+	if plane == nil {
+		return 0
+	}
+	// Only the low frequency band (LL) is kept at each level
+	for size > 8 {
+		plane, size, _ = halvePlane(plane, size, size)
+	}
+	values := make([]float64, 0, 64)
+	for _, row := range plane {
+		values = append(values, row...)
+	}
+	return hashBits(values, median(values))
+}
+
+// AverageHash computes the average hash of the image brightness.
+func (ppm *PPM) AverageHash() ImageHash {
+	return ppm.ToPGM().AverageHash()
+}
+
+// DifferenceHash computes the difference hash of the image brightness.
+func (ppm *PPM) DifferenceHash() ImageHash {
+	return ppm.ToPGM().DifferenceHash()
+}
+
+// PerceptualHash computes the DCT hash of the image brightness.
+func (ppm *PPM) PerceptualHash() ImageHash {
+	return ppm.ToPGM().PerceptualHash()
+}
+
+// WaveletHash computes the Haar wavelet hash of the image brightness.
+func (ppm *PPM) WaveletHash() ImageHash {
+	return ppm.ToPGM().WaveletHash()
+}
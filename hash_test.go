@@ -0,0 +1,99 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+// hashScene is a smooth pattern with a few large features.
+func hashScene(w, h int) *PGM {
+	return newTestPGM(w, h, func(x, y int) uint16 {
+		fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+		return uint16(128 + 90*math.Sin(7*fx)*math.Cos(5*fy+1))
+	})
+}
+
+// otherScene shares nothing with hashScene.
+func otherScene(w, h int) *PGM {
+	return newTestPGM(w, h, func(x, y int) uint16 {
+		fx, fy := float64(x)/float64(w)-0.3, float64(y)/float64(h)-0.6
+		return uint16(128 + 120*math.Cos(20*math.Hypot(fx, fy)))
+	})
+}
+
+type hashFunc struct {
+	name string
+	hash func(*PGM) ImageHash
+	want ImageHash
+}
+
+var hashFuncs = []hashFunc{
+	{"average", (*PGM).AverageHash, 0xf10e0e0e0e0ef1f1},
+	{"difference", (*PGM).DifferenceHash, 0x3cc3c3c3c3c33c3c},
+	{"perceptual", (*PGM).PerceptualHash, 0x3fc0c1c1c5c5d5d5},
+	{"wavelet", (*PGM).WaveletHash, 0xf11e0e0e0e1ef1f1},
+}
+
+func TestHashPinned(t *testing.T) {
+	scene := hashScene(128, 96)
+	for _, f := range hashFuncs {
+		if got := f.hash(scene); got != f.want {
+			t.Errorf("%s hash = %v, want %v", f.name, got, f.want)
+		}
+	}
+}
+
+func TestHashRobustness(t *testing.T) {
+	scene := hashScene(128, 96)
+	for _, f := range hashFuncs {
+		h := f.hash(scene)
+
+		scaled := hashScene(128, 96)
+		if err := scaled.Resize(200, 150, FilterBilinear); err != nil {
+			t.Fatal(err)
+		}
+		if d := h.Distance(f.hash(scaled)); d > 4 {
+			t.Errorf("%s: distance %d after rescaling", f.name, d)
+		}
+
+		blurred := hashScene(128, 96)
+		blurred.GaussianBlur(1.5)
+		if d := h.Distance(f.hash(blurred)); d > 4 {
+			t.Errorf("%s: distance %d after blurring", f.name, d)
+		}
+
+		if d := h.Distance(f.hash(otherScene(128, 96))); d < 20 {
+			t.Errorf("%s: distance %d to an unrelated image", f.name, d)
+		}
+	}
+}
+
+func TestHashHelpers(t *testing.T) {
+	a, b := ImageHash(0xff00), ImageHash(0x0f01)
+	if HammingDistance(a, b) != 5 || a.Distance(b) != 5 {
+		t.Fatalf("distance %d, want 5", a.Distance(b))
+	}
+	if !a.Similar(b, 5) || a.Similar(b, 4) {
+		t.Fatal("unexpected Similar result")
+	}
+	if a.String() != "000000000000ff00" {
+		t.Fatalf("String() = %q", a.String())
+	}
+	empty := &PGM{magicNumber: "P2", max: 255}
+	for _, f := range hashFuncs {
+		if f.hash(empty) != 0 {
+			t.Errorf("%s hash of an empty image is not 0", f.name)
+		}
+	}
+}
+
+func TestPPMHashMatchesGray(t *testing.T) {
+	gray := hashScene(64, 64)
+	ppm := newTestPPM(64, 64, func(x, y int) Pixel {
+		v := gray.data[y][x]
+		return Pixel{v, v, v}
+	})
+	if ppm.PerceptualHash() != gray.PerceptualHash() || ppm.AverageHash() != gray.AverageHash() {
+		t.Fatal("a gray PPM should hash like the PGM")
+	}
+}
@@ -0,0 +1,330 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Histogram holds, for every sample value from 0 to maxval, the number of
+// samples having that value.
+type Histogram []int
+
+// Histogram computes the histogram of the image. It has maxval+1 entries.
+func (pgm *PGM) Histogram() Histogram {
+	return planeHistogram(pgm.data, pgm.max)
+}
+
+// Histograms computes the histograms of the red, green and blue channels.
+func (ppm *PPM) Histograms() (Histogram, Histogram, Histogram) {
+	var h [3]Histogram
+	for c := range h {
+		h[c] = make(Histogram, ppm.max+1)
+	}
+	for y := range ppm.data {
+		for _, p := range ppm.data[y] {
+			for c := range h {
+				h[c][clamp(int(Channel(c).get(p)), 0, ppm.max)]++
+			}
+		}
+	}
+	return h[0], h[1], h[2]
+}
+
+func planeHistogram(data [][]uint16, max int) Histogram {
+	h := make(Histogram, max+1)
+	for y := range data {
+		for _, v := range data[y] {
+			h[clamp(int(v), 0, max)]++
+		}
+	}
+	return h
+}
+
+// Total returns the number of samples counted.
+func (h Histogram) Total() int {
+	total := 0
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+
+// Cumulative returns the cumulative counts: entry v is the number of samples
+// less than or equal to v.
+func (h Histogram) Cumulative() []int {
+	cum := make([]int, len(h))
+	sum := 0
+	for v, n := range h {
+		sum += n
+		cum[v] = sum
+	}
+	return cum
+}
+
+// CDF returns the cumulative distribution function, normalized to [0, 1].
+func (h Histogram) CDF() []float64 {
+	cdf := make([]float64, len(h))
+	total := float64(h.Total())
+	if total == 0 {
+		return cdf
+	}
+	for v, n := range h.Cumulative() {
+		cdf[v] = float64(n) / total
+	}
+	return cdf
+}
+
+// Min returns the smallest sample value present, or -1 if the histogram is
+// empty.
+func (h Histogram) Min() int {
+	for v, n := range h {
+		if n > 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// Max returns the largest sample value present, or -1 if the histogram is
+// empty.
+func (h Histogram) Max() int {
+	for v := len(h) - 1; v >= 0; v-- {
+		if h[v] > 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// Mean returns the mean sample value.
+func (h Histogram) Mean() float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	sum := 0.0
+	for v, n := range h {
+		sum += float64(v) * float64(n)
+	}
+	return sum / float64(total)
+}
+
+// StdDev returns the population standard deviation of the samples.
+func (h Histogram) StdDev() float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	mean := h.Mean()
+	sum := 0.0
+	for v, n := range h {
+		d := float64(v) - mean
+		sum += d * d * float64(n)
+	}
+	return math.Sqrt(sum / float64(total))
+}
+
+// Percentile returns the smallest sample value v such that at least p percent
+// of the samples are less than or equal to v. p is in [0, 100].
+func (h Histogram) Percentile(p float64) int {
+	total := h.Total()
+	if total == 0 {
+		return -1
+	}
+	target := p / 100 * float64(total)
+	sum := 0
+	for v, n := range h {
+		sum += n
+		if n > 0 && float64(sum) >= target {
+			return v
+		}
+	}
+	return h.Max()
+}
+
+// Median returns the median sample value.
+func (h Histogram) Median() int {
+	return h.Percentile(50)
+}
+
+// equalizeLUT builds the lookup table spreading the histogram over [0, max].
+func (h Histogram) equalizeLUT(max int) []uint16 {
+	cum := h.Cumulative()
+	total := h.Total()
+	cdfMin := 0
+	for _, c := range cum {
+		if c > 0 {
+			cdfMin = c
+			break
+		}
+	}
+
+	lut := make([]uint16, len(h))
+	if total == cdfMin {
+		// Uniform image: nothing to equalize
+		for v := range lut {
+			lut[v] = uint16(v)
+		}
+		return lut
+	}
+	for v, c := range cum {
+		t := float64(c-cdfMin) / float64(total-cdfMin)
+		lut[v] = unitToSample(t, max)
+	}
+	return lut
+}
+
+// matchLUT builds the lookup table mapping the distribution of h onto the one
+// of reference. Both histograms must have the same number of entries.
+func (h Histogram) matchLUT(reference Histogram) []uint16 {
+	src := h.CDF()
+	ref := reference.CDF()
+	lut := make([]uint16, len(h))
+	u := 0
+	for v := range lut {
+		for u < len(ref)-1 && ref[u] < src[v] {
+			u++
+		}
+		lut[v] = uint16(u)
+	}
+	return lut
+}
+
+// Equalize applies global histogram equalization. It fails if the image's
+// maxval is invalid.
+func (pgm *PGM) Equalize() error {
+	if err := checkMaxValue(pgm.max); err != nil {
+		return err
+	}
+	return pgm.ApplyLUT(pgm.Histogram().equalizeLUT(pgm.max))
+}
+
+// Equalize applies histogram equalization to each channel separately.
+func (ppm *PPM) Equalize() error {
+	if err := checkMaxValue(ppm.max); err != nil {
+		return err
+	}
+	r, g, b := ppm.Histograms()
+	return ppm.ApplyLUT(r.equalizeLUT(ppm.max), g.equalizeLUT(ppm.max), b.equalizeLUT(ppm.max))
+}
+
+// MatchHistogram remaps the samples so that the histogram of the image
+// resembles the one of a reference image.
+func (pgm *PGM) MatchHistogram(reference *PGM) error {
+	if reference.max != pgm.max {
+		return fmt.Errorf("reference max value %d differs from %d", reference.max, pgm.max)
+	}
+	return pgm.ApplyLUT(pgm.Histogram().matchLUT(reference.Histogram()))
+}
+
+// MatchHistogram matches each channel to the same channel of a reference image.
+func (ppm *PPM) MatchHistogram(reference *PPM) error {
+	if reference.max != ppm.max {
+		return fmt.Errorf("reference max value %d differs from %d", reference.max, ppm.max)
+	}
+	r, g, b := ppm.Histograms()
+	rr, rg, rb := reference.Histograms()
+	return ppm.ApplyLUT(r.matchLUT(rr), g.matchLUT(rg), b.matchLUT(rb))
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization. The image is
+// divided into tilesX by tilesY tiles, each equalized with its histogram
+// clipped at clipLimit times the average bin count, and the mappings are
+// blended bilinearly between tile centers.
+func (pgm *PGM) CLAHE(tilesX, tilesY int, clipLimit float64) error {
+	if tilesX < 1 || tilesY < 1 {
+		return fmt.Errorf("invalid tile grid: %dx%d", tilesX, tilesY)
+	}
+	pgm.data = storeGrid(pgm.data, clahePlane(pgm.data, pgm.width, pgm.height, pgm.max, tilesX, tilesY, clipLimit))
+	return nil
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to each
+// channel separately.
+func (ppm *PPM) CLAHE(tilesX, tilesY int, clipLimit float64) error {
+	if tilesX < 1 || tilesY < 1 {
+		return fmt.Errorf("invalid tile grid: %dx%d", tilesX, tilesY)
+	}
+	ppm.mapChannels(ppm.width, ppm.height, func(c Channel, plane [][]uint16) [][]uint16 {
+		return clahePlane(plane, ppm.width, ppm.height, ppm.max, tilesX, tilesY, clipLimit)
+	})
+	return nil
+}
+
+func clahePlane(data [][]uint16, width, height, max, tilesX, tilesY int, clipLimit float64) [][]uint16 {
+	if tilesX > width {
+		tilesX = width
+	}
+	if tilesY > height {
+		tilesY = height
+	}
+	if tilesX < 1 || tilesY < 1 {
+		return data
+	}
+
+	// One lookup table per tile
+	luts := make([][][]uint16, tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		luts[ty] = make([][]uint16, tilesX)
+		y0, y1 := ty*height/tilesY, (ty+1)*height/tilesY
+		for tx := 0; tx < tilesX; tx++ {
+			x0, x1 := tx*width/tilesX, (tx+1)*width/tilesX
+			h := make(Histogram, max+1)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					h[clamp(int(data[y][x]), 0, max)]++
+				}
+			}
+			if clipLimit > 0 {
+				clipHistogram(h, clipLimit)
+			}
+			luts[ty][tx] = h.equalizeLUT(max)
+		}
+	}
+
+	tileW := float64(width) / float64(tilesX)
+	tileH := float64(height) / float64(tilesY)
+	out := make([][]uint16, height)
+	for y := 0; y < height; y++ {
+		out[y] = make([]uint16, width)
+		fy := (float64(y)+0.5)/tileH - 0.5
+		ty0 := clamp(int(math.Floor(fy)), 0, tilesY-1)
+		ty1 := clamp(ty0+1, 0, tilesY-1)
+		wy := clampUnit(fy - float64(ty0))
+		for x := 0; x < width; x++ {
+			fx := (float64(x)+0.5)/tileW - 0.5
+			tx0 := clamp(int(math.Floor(fx)), 0, tilesX-1)
+			tx1 := clamp(tx0+1, 0, tilesX-1)
+			wx := clampUnit(fx - float64(tx0))
+
+			v := clamp(int(data[y][x]), 0, max)
+			top := (1-wx)*float64(luts[ty0][tx0][v]) + wx*float64(luts[ty0][tx1][v])
+			bottom := (1-wx)*float64(luts[ty1][tx0][v]) + wx*float64(luts[ty1][tx1][v])
+			out[y][x] = uint16(math.Round((1-wy)*top + wy*bottom))
+		}
+	}
+	return out
+}
+
+// clipHistogram limits every bin to clipLimit times the average bin count and
+// spreads the excess evenly over all bins.
+func clipHistogram(h Histogram, clipLimit float64) {
+	limit := int(clipLimit * float64(h.Total()) / float64(len(h)))
+	if limit < 1 {
+		limit = 1
+	}
+	excess := 0
+	for v, n := range h {
+		if n > limit {
+			excess += n - limit
+			h[v] = limit
+		}
+	}
+	share, rest := excess/len(h), excess%len(h)
+	for v := range h {
+		h[v] += share
+		if v < rest {
+			h[v]++
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package Netpbm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramStatistics(t *testing.T) {
+	samples := []uint16{0, 2, 2, 4, 7}
+	pgm := newTestPGM(len(samples), 1, func(x, y int) uint16 { return samples[x] })
+	pgm.max = 7
+	h := pgm.Histogram()
+	if len(h) != 8 || h[2] != 2 || h.Total() != 5 {
+		t.Fatalf("got %v", h)
+	}
+	if h.Min() != 0 || h.Max() != 7 || h.Median() != 2 {
+		t.Errorf("min %d, max %d, median %d", h.Min(), h.Max(), h.Median())
+	}
+	if math.Abs(h.Mean()-3) > 1e-9 {
+		t.Errorf("mean %g, want 3", h.Mean())
+	}
+	if math.Abs(h.StdDev()-math.Sqrt(5.6)) > 1e-9 {
+		t.Errorf("stddev %g, want %g", h.StdDev(), math.Sqrt(5.6))
+	}
+	if cdf := h.CDF(); cdf[7] != 1 || cdf[2] != 0.6 {
+		t.Errorf("cdf %v", cdf)
+	}
+	empty := make(Histogram, 4)
+	if empty.Min() != -1 || empty.Max() != -1 || empty.Median() != -1 {
+		t.Error("empty histogram should report -1")
+	}
+}
+
+func TestEqualizeSpreadsRange(t *testing.T) {
+	pgm := newTestPGM(4, 4, func(x, y int) uint16 { return uint16(100 + x + 4*y) })
+	if err := pgm.Equalize(); err != nil {
+		t.Fatal(err)
+	}
+	h := pgm.Histogram()
+	if h.Min() != 0 || h.Max() != 255 {
+		t.Fatalf("range [%d, %d], want [0, 255]", h.Min(), h.Max())
+	}
+	prev := -1
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if v := int(pgm.data[y][x]); v <= prev {
+				t.Fatalf("order not preserved at (%d, %d)", x, y)
+			} else {
+				prev = v
+			}
+		}
+	}
+
+	flat := newTestPGM(3, 3, func(x, y int) uint16 { return 42 })
+	if err := flat.Equalize(); err != nil {
+		t.Fatal(err)
+	}
+	if flat.data[1][1] != 42 {
+		t.Fatalf("uniform image changed to %d", flat.data[1][1])
+	}
+	flat.max = 0
+	if err := flat.Equalize(); err == nil {
+		t.Fatal("Equalize accepted a maxval of 0")
+	}
+}
+
+func TestMatchHistogram(t *testing.T) {
+	pgm := newTestPGM(4, 1, func(x, y int) uint16 { return uint16(10 * x) })
+	reference := newTestPGM(4, 1, func(x, y int) uint16 { return uint16(200 + 10*x) })
+	if err := pgm.MatchHistogram(reference); err != nil {
+		t.Fatal(err)
+	}
+	for x, want := range []uint16{200, 210, 220, 230} {
+		if got := pgm.data[0][x]; got != want {
+			t.Errorf("x=%d: got %d, want %d", x, got, want)
+		}
+	}
+	reference.max = 100
+	if err := pgm.MatchHistogram(reference); err == nil {
+		t.Fatal("reference with a different max value accepted")
+	}
+}
+
+func TestCLAHE(t *testing.T) {
+	fill := func(x, y int) uint16 { return uint16(100 + 3*x + y) }
+	single := newTestPGM(8, 8, fill)
+	if err := single.CLAHE(1, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	global := newTestPGM(8, 8, fill)
+	if err := global.Equalize(); err != nil {
+		t.Fatal(err)
+	}
+	for y := range single.data {
+		for x := range single.data[y] {
+			if single.data[y][x] != global.data[y][x] {
+				t.Fatalf("one unclipped tile should equal Equalize at (%d, %d): %d vs %d", x, y, single.data[y][x], global.data[y][x])
+			}
+		}
+	}
+
+	// Clipping limits how far the contrast of a low-contrast image is stretched.
+	low := newTestPGM(8, 8, func(x, y int) uint16 { return uint16(120 + x/4) })
+	if err := low.CLAHE(2, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if h := low.Histogram(); h.Max()-h.Min() >= 255 {
+		t.Errorf("clipped CLAHE stretched to the full range: [%d, %d]", h.Min(), h.Max())
+	}
+	if err := low.CLAHE(0, 2, 1); err == nil {
+		t.Error("empty tile grid accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package Netpbm
+
+import "math"
+
+// Interpolation selects how samples are read between pixel centers when an
+// image is resampled.
+type Interpolation int
+
+const (
+	// NearestNeighbor takes the closest pixel.
+	NearestNeighbor Interpolation = iota
+	// Bilinear blends the 2x2 surrounding pixels.
+	Bilinear
+	// Bicubic blends the 4x4 surrounding pixels with a Catmull-Rom spline.
+	Bicubic
+)
+
+// pointMapping maps the coordinates of an output pixel center to coordinates
+// in the source image, where pixel (x, y) is centered on (x, y). ok is false
+// when the point has no source.
+type pointMapping func(x, y float64) (sx, sy float64, ok bool)
+
+// planeAt returns the sample at (x, y), or background outside the plane.
+func planeAt(data [][]uint16, width, height, x, y int, background float64) float64 {
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return background
+	}
+	return float64(data[y][x])
+}
+
+// samplePlane reads a plane at real coordinates using the given interpolation.
+// Pixels outside the plane count as background, which smooths the borders.
+func samplePlane(data [][]uint16, width, height int, x, y float64, interp Interpolation, background float64) float64 {
+	switch interp {
+	case Bilinear:
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+		top := (1-fx)*planeAt(data, width, height, ix, iy, background) + fx*planeAt(data, width, height, ix+1, iy, background)
+		bottom := (1-fx)*planeAt(data, width, height, ix, iy+1, background) + fx*planeAt(data, width, height, ix+1, iy+1, background)
+		return (1-fy)*top + fy*bottom
+	case Bicubic:
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+		var wx, wy [4]float64
+		for i := 0; i < 4; i++ {
+			wx[i] = catmullRom(fx - float64(i-1))
+			wy[i] = catmullRom(fy - float64(i-1))
+		}
+		sum := 0.0
+		for j := 0; j < 4; j++ {
+			row := 0.0
+			for i := 0; i < 4; i++ {
+				row += wx[i] * planeAt(data, width, height, ix+i-1, iy+j-1, background)
+			}
+			sum += wy[j] * row
+		}
+		return sum
+	}
+	return planeAt(data, width, height, int(math.Floor(x+0.5)), int(math.Floor(y+0.5)), background)
+}
+
+// catmullRom is the Catmull-Rom cubic convolution kernel (a = -0.5).
+func catmullRom(t float64) float64 {
+	t = math.Abs(t)
+	switch {
+	case t < 1:
+		return 1.5*t*t*t - 2.5*t*t + 1
+	case t < 2:
+		return -0.5*t*t*t + 2.5*t*t - 4*t + 2
+	}
+	return 0
+}
+
+// remapPlane builds an outWidth x outHeight plane by sampling the source plane
+// at the coordinates given by mapping for each output pixel.
+func remapPlane(data [][]uint16, width, height, outWidth, outHeight int, mapping pointMapping, interp Interpolation, background uint16, max int) [][]uint16 {
+	out := newGrid[uint16](outWidth, outHeight)
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			sx, sy, ok := mapping(float64(x), float64(y))
+			if !ok {
+				out[y][x] = background
+				continue
+			}
+			v := samplePlane(data, width, height, sx, sy, interp, float64(background))
+			out[y][x] = uint16(clamp(int(math.Round(v)), 0, max))
+		}
+	}
+	return out
+}
+
+// remapNearest is the nearest-neighbour version of remapPlane for grids of
+// any type.
+func remapNearest[T any](data [][]T, width, height, outWidth, outHeight int, mapping pointMapping, background T) [][]T {
+	out := newGrid[T](outWidth, outHeight)
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			out[y][x] = background
+			sx, sy, ok := mapping(float64(x), float64(y))
+			if !ok {
+				continue
+			}
+			ix, iy := int(math.Floor(sx+0.5)), int(math.Floor(sy+0.5))
+			if ix >= 0 && iy >= 0 && ix < width && iy < height {
+				out[y][x] = data[iy][ix]
+			}
+		}
+	}
+	return out
+}
+
+// remapPPM applies remapPlane to the three channels of a PPM image.
+func remapPPM(ppm *PPM, outWidth, outHeight int, mapping pointMapping, interp Interpolation, background Pixel) {
+	if interp == NearestNeighbor {
+		ppm.data = storeGrid(ppm.data, remapNearest(ppm.data, ppm.width, ppm.height, outWidth, outHeight, mapping, background))
+		ppm.width, ppm.height = outWidth, outHeight
+		return
+	}
+	width, height := ppm.width, ppm.height
+	ppm.mapChannels(outWidth, outHeight, func(c Channel, plane [][]uint16) [][]uint16 {
+		return remapPlane(plane, width, height, outWidth, outHeight, mapping, interp, c.get(background), ppm.max)
+	})
+}
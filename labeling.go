@@ -0,0 +1,152 @@
+package Netpbm
+
+// LabelImage assigns to each pixel the number of the connected component it
+// belongs to. Background pixels have label 0 and components are numbered
+// from 1 to Count in raster order.
+type LabelImage struct {
+	Width, Height int
+	// Labels is indexed [y][x].
+	Labels [][]int
+	Count  int
+}
+
+// ComponentStats describes one connected component.
+type ComponentStats struct {
+	Label int
+	// Area is the number of pixels.
+	Area int
+	// Bounds is the smallest rectangle holding the component.
+	Bounds Rectangle
+	// CentroidX and CentroidY are the mean pixel coordinates.
+	CentroidX, CentroidY float64
+	// Perimeter is the number of pixel edges between the component and the
+	// background or the image border.
+	Perimeter int
+	// Holes is the number of background regions enclosed by the component.
+	Holes int
+}
+
+// Label finds the connected components of black (true) pixels with the given
+// connectivity and returns the label image along with the statistics of each
+// component; stats[i] describes label i+1. Holes are counted with the
+// complementary connectivity so that the background and foreground stay
+// consistent.
+func (pbm *PBM) Label(conn Connectivity) (*LabelImage, []ComponentStats) {
+	w, h := pbm.width, pbm.height
+	labels, count := labelGrid(w, h, conn, func(x, y int) bool { return pbm.data[y][x] })
+	result := &LabelImage{Width: w, Height: h, Labels: labels, Count: count}
+
+	stats := make([]ComponentStats, count)
+	for i := range stats {
+		stats[i] = ComponentStats{Label: i + 1, Bounds: Rectangle{Min: Point{w, h}}}
+	}
+	sumX := make([]float64, count)
+	sumY := make([]float64, count)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := labels[y][x]
+			if l == 0 {
+				continue
+			}
+			s := &stats[l-1]
+			s.Area++
+			sumX[l-1] += float64(x)
+			sumY[l-1] += float64(y)
+			s.Bounds.Min.X = minInt(s.Bounds.Min.X, x)
+			s.Bounds.Min.Y = minInt(s.Bounds.Min.Y, y)
+			if x+1 > s.Bounds.Max.X {
+				s.Bounds.Max.X = x + 1
+			}
+			if y+1 > s.Bounds.Max.Y {
+				s.Bounds.Max.Y = y + 1
+			}
+			for _, n := range Connectivity4.offsets() {
+				nx, ny := x+n.X, y+n.Y
+				if nx < 0 || ny < 0 || nx >= w || ny >= h || labels[ny][nx] != l {
+					s.Perimeter++
+				}
+			}
+		}
+	}
+	for i := range stats {
+		stats[i].CentroidX = sumX[i] / float64(stats[i].Area)
+		stats[i].CentroidY = sumY[i] / float64(stats[i].Area)
+	}
+
+	// Holes: background regions that do not touch the border
+	background := Connectivity4
+	if conn == Connectivity4 {
+		background = Connectivity8
+	}
+	bgLabels, bgCount := labelGrid(w, h, background, func(x, y int) bool { return !pbm.data[y][x] })
+	seen := make([]bool, bgCount+1)
+	touchesBorder := make([]bool, bgCount+1)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if l := bgLabels[y][x]; l != 0 && (x == 0 || y == 0 || x == w-1 || y == h-1) {
+				touchesBorder[l] = true
+			}
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := bgLabels[y][x]
+			if l == 0 || seen[l] {
+				continue
+			}
+			seen[l] = true
+			// The pixel above the first pixel of a hole belongs to the enclosing component
+			if !touchesBorder[l] && y > 0 && labels[y-1][x] != 0 {
+				stats[labels[y-1][x]-1].Holes++
+			}
+		}
+	}
+	return result, stats
+}
+
+// Mask returns a PBM image where the pixels of the given component are black.
+func (l *LabelImage) Mask(label int) *PBM {
+	mask := &PBM{data: newGrid[bool](l.Width, l.Height), width: l.Width, height: l.Height, magicNumber: "P1"}
+	for y := range l.Labels {
+		for x, v := range l.Labels[y] {
+			mask.data[y][x] = v == label
+		}
+	}
+	return mask
+}
+
+// ToPGM renders the labels as gray levels, for display. Labels above 255 wrap
+// around.
+func (l *LabelImage) ToPGM() *PGM {
+	data := newGrid[uint16](l.Width, l.Height)
+	for y := range l.Labels {
+		for x, v := range l.Labels[y] {
+			if v != 0 {
+				data[y][x] = uint16((v-1)%255 + 1)
+			}
+		}
+	}
+	return &PGM{data: data, width: l.Width, height: l.Height, magicNumber: "P2", max: 255}
+}
+
+// RemoveSmallComponents turns white every component of black pixels having
+// fewer than minArea pixels, and returns how many were removed.
+func (pbm *PBM) RemoveSmallComponents(minArea int, conn Connectivity) int {
+	labels, stats := pbm.Label(conn)
+	removed := 0
+	small := make([]bool, len(stats)+1)
+	for _, s := range stats {
+		if s.Area < minArea {
+			small[s.Label] = true
+			removed++
+		}
+	}
+	for y := range labels.Labels {
+		for x, l := range labels.Labels[y] {
+			if small[l] {
+				pbm.data[y][x] = false
+			}
+		}
+	}
+	return removed
+}
@@ -0,0 +1,92 @@
+package Netpbm
+
+import "testing"
+
+func TestLabelConnectivity(t *testing.T) {
+	pbm := newTestPBM(
+		"##...",
+		"##...",
+		"..#..",
+		"...#.",
+	)
+	labels, stats := pbm.Label(Connectivity4)
+	if labels.Count != 3 || len(stats) != 3 {
+		t.Fatalf("4-connectivity: got %d components, want 3", labels.Count)
+	}
+	labels, stats = pbm.Label(Connectivity8)
+	if labels.Count != 1 || len(stats) != 1 {
+		t.Fatalf("8-connectivity: got %d components, want 1", labels.Count)
+	}
+	if labels.Labels[0][0] != 1 || labels.Labels[3][3] != 1 || labels.Labels[0][4] != 0 {
+		t.Fatalf("unexpected labels %v", labels.Labels)
+	}
+}
+
+func TestLabelStats(t *testing.T) {
+	pbm := newTestPBM(
+		"........",
+		".#####..",
+		".#...#..",
+		".#####..",
+		"......##",
+	)
+	_, stats := pbm.Label(Connectivity4)
+	if len(stats) != 2 {
+		t.Fatalf("got %d components, want 2", len(stats))
+	}
+	ring := stats[0]
+	if ring.Label != 1 || ring.Area != 12 {
+		t.Fatalf("ring: label %d area %d, want 1 and 12", ring.Label, ring.Area)
+	}
+	if ring.Bounds != (Rectangle{Min: Point{1, 1}, Max: Point{6, 4}}) {
+		t.Fatalf("ring bounds %v", ring.Bounds)
+	}
+	if ring.CentroidX != 3 || ring.CentroidY != 2 {
+		t.Fatalf("ring centroid (%g, %g), want (3, 2)", ring.CentroidX, ring.CentroidY)
+	}
+	// 5x3 outline: 16 outer edges plus 8 edges around the 3x1 hole.
+	if ring.Perimeter != 24 {
+		t.Fatalf("ring perimeter %d, want 24", ring.Perimeter)
+	}
+	if ring.Holes != 1 {
+		t.Fatalf("ring holes %d, want 1", ring.Holes)
+	}
+	bar := stats[1]
+	if bar.Area != 2 || bar.Holes != 0 || bar.Perimeter != 6 {
+		t.Fatalf("bar: %+v", bar)
+	}
+}
+
+func TestLabelMaskAndToPGM(t *testing.T) {
+	pbm := newTestPBM(
+		"#.#",
+		"#.#",
+	)
+	labels, _ := pbm.Label(Connectivity8)
+	mask := labels.Mask(2)
+	want := []string{"..#", "..#"}
+	for y, row := range pbmRows(mask) {
+		if row != want[y] {
+			t.Fatalf("mask %v, want %v", pbmRows(mask), want)
+		}
+	}
+	pgm := labels.ToPGM()
+	if pgm.data[0][0] != 1 || pgm.data[0][1] != 0 || pgm.data[1][2] != 2 {
+		t.Fatalf("label image %v", pgm.data)
+	}
+}
+
+func TestRemoveSmallComponents(t *testing.T) {
+	pbm := newTestPBM(
+		"###..#",
+		"###...",
+		"......",
+		"#.....",
+	)
+	if removed := pbm.RemoveSmallComponents(2, Connectivity8); removed != 2 {
+		t.Fatalf("removed %d components, want 2", removed)
+	}
+	if pbm.data[0][5] || pbm.data[3][0] || !pbm.data[1][1] {
+		t.Fatalf("unexpected result %v", pbmRows(pbm))
+	}
+}
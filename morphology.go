@@ -0,0 +1,428 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// StructuringElement is the shape probing an image in morphological
+// operations. The origin is the cell placed over the pixel being computed.
+type StructuringElement struct {
+	Width, Height    int
+	OriginX, OriginY int
+	cells            [][]bool
+}
+
+// NewStructuringElement builds an element from its cells, indexed [y][x],
+// with the given origin.
+func NewStructuringElement(cells [][]bool, originX, originY int) (StructuringElement, error) {
+	height := len(cells)
+	if height == 0 || len(cells[0]) == 0 {
+		return StructuringElement{}, fmt.Errorf("empty structuring element")
+	}
+	width := len(cells[0])
+	if originX < 0 || originY < 0 || originX >= width || originY >= height {
+		return StructuringElement{}, fmt.Errorf("origin (%d, %d) outside of the structuring element", originX, originY)
+	}
+	se := StructuringElement{Width: width, Height: height, OriginX: originX, OriginY: originY, cells: newGrid[bool](width, height)}
+	for y := range cells {
+		if len(cells[y]) != width {
+			return StructuringElement{}, fmt.Errorf("structuring element rows have different lengths")
+		}
+		copy(se.cells[y], cells[y])
+	}
+	return se, nil
+}
+
+// SquareElement returns a size x size square centered on its origin.
+func SquareElement(size int) StructuringElement {
+	return shapeElement(size, func(x, y, r int) bool { return true })
+}
+
+// CrossElement returns a size x size cross (the center row and column).
+func CrossElement(size int) StructuringElement {
+	return shapeElement(size, func(x, y, r int) bool { return x == r || y == r })
+}
+
+// DiskElement returns a disk of the given radius.
+func DiskElement(radius int) StructuringElement {
+	return shapeElement(2*radius+1, func(x, y, r int) bool { return (x-r)*(x-r)+(y-r)*(y-r) <= r*r })
+}
+
+func shapeElement(size int, inside func(x, y, r int) bool) StructuringElement {
+	if size < 1 {
+		size = 1
+	}
+	r := size / 2
+	cells := newGrid[bool](size, size)
+	for y := range cells {
+		for x := range cells[y] {
+			cells[y][x] = inside(x, y, r)
+		}
+	}
+	return StructuringElement{Width: size, Height: size, OriginX: r, OriginY: r, cells: cells}
+}
+
+// ElementFromPBM uses the black pixels of a PBM image as a structuring
+// element.
+func ElementFromPBM(pbm *PBM, originX, originY int) (StructuringElement, error) {
+	return NewStructuringElement(pbm.data, originX, originY)
+}
+
+// At reports whether the cell (x, y) of the element is set.
+func (se StructuringElement) At(x, y int) bool {
+	if x < 0 || y < 0 || x >= se.Width || y >= se.Height {
+		return false
+	}
+	return se.cells[y][x]
+}
+
+// offsets lists the set cells relative to the origin.
+func (se StructuringElement) offsets() []Point {
+	var offsets []Point
+	for y := 0; y < se.Height; y++ {
+		for x := 0; x < se.Width; x++ {
+			if se.cells[y][x] {
+				offsets = append(offsets, Point{x - se.OriginX, y - se.OriginY})
+			}
+		}
+	}
+	return offsets
+}
+
+// Connectivity tells which pixels are neighbours.
+type Connectivity int
+
+const (
+	// Connectivity4 links pixels sharing an edge.
+	Connectivity4 Connectivity = 4
+	// Connectivity8 also links pixels sharing a corner.
+	Connectivity8 Connectivity = 8
+)
+
+// offsets lists the neighbours of a pixel.
+func (c Connectivity) offsets() []Point {
+	if c == Connectivity8 {
+		return []Point{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
+	}
+	return []Point{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+}
+
+
+// unionFind is a disjoint-set forest used to merge provisional labels.
+type unionFind []int
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(a, b int) {
+	a, b = u.find(a), u.find(b)
+	if a < b {
+		u[b] = a
+	} else if b < a {
+		u[a] = b
+	}
+}
+
+// labelGrid labels the pixels for which value is true with a two-pass
+// algorithm and returns the labels and the number of components.
+func labelGrid(width, height int, conn Connectivity, value func(x, y int) bool) ([][]int, int) {
+	labels := newGrid[int](width, height)
+	parent := unionFind{0}
+
+	// Neighbours already visited in raster order
+	previous := []Point{{-1, 0}, {0, -1}}
+	if conn == Connectivity8 {
+		previous = append(previous, Point{-1, -1}, Point{1, -1})
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !value(x, y) {
+				continue
+			}
+			label := 0
+			for _, n := range previous {
+				nx, ny := x+n.X, y+n.Y
+				if nx < 0 || ny < 0 || nx >= width || labels[ny][nx] == 0 {
+					continue
+				}
+				if label == 0 {
+					label = labels[ny][nx]
+				} else {
+					parent.union(label, labels[ny][nx])
+				}
+			}
+			if label == 0 {
+				label = len(parent)
+				parent = append(parent, label)
+			}
+			labels[y][x] = label
+		}
+	}
+
+	// Renumber the labels from 1 to count
+	final := make([]int, len(parent))
+	count := 0
+	for i := 1; i < len(parent); i++ {
+		root := parent.find(i)
+		if final[root] == 0 {
+			count++
+			final[root] = count
+		}
+		final[i] = final[root]
+	}
+	for y := range labels {
+		for x, l := range labels[y] {
+			labels[y][x] = final[l]
+		}
+	}
+	return labels, count
+}
+
+// bitmap stores a binary image with 64 pixels per word, so that
+// morphological operations handle whole words at once. Pixel x of a row is
+// bit x%64 of word x/64; bits past the width are always zero.
+type bitmap struct {
+	width, height, words int
+	rows                 [][]uint64
+}
+
+func newBitmap(width, height int) *bitmap {
+	words := (width + 63) / 64
+	return &bitmap{width: width, height: height, words: words, rows: newGrid[uint64](words, height)}
+}
+
+func packBits(data [][]bool, width, height int) *bitmap {
+	b := newBitmap(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if data[y][x] {
+				b.rows[y][x/64] |= 1 << uint(x%64)
+			}
+		}
+	}
+	return b
+}
+
+func (b *bitmap) unpack() [][]bool {
+	data := newGrid[bool](b.width, b.height)
+	for y := range data {
+		for x := range data[y] {
+			data[y][x] = b.rows[y][x/64]&(1<<uint(x%64)) != 0
+		}
+	}
+	return data
+}
+
+// tailMask returns the bits of the last word that belong to the image.
+func (b *bitmap) tailMask() uint64 {
+	if r := b.width % 64; r != 0 {
+		return 1<<uint(r) - 1
+	}
+	return ^uint64(0)
+}
+
+// setRange sets the bits [from, to) of a row.
+func setRange(row []uint64, from, to int) {
+	for x := from; x < to; x++ {
+		row[x/64] |= 1 << uint(x%64)
+	}
+}
+
+// shiftRow writes into dst the row read s pixels further: dst[x] = src[x+s].
+// Pixels read outside the row take the value fill.
+func (b *bitmap) shiftRow(src, dst []uint64, s int, fill bool) {
+	q, r := s/64, uint(s%64)
+	if s < 0 {
+		q, r = (-s)/64, uint((-s)%64)
+	}
+	for w := range dst {
+		var v uint64
+		if s >= 0 {
+			if i := w + q; i < len(src) {
+				v = src[i] >> r
+				if r != 0 && i+1 < len(src) {
+					v |= src[i+1] << (64 - r)
+				}
+			}
+		} else {
+			if i := w - q; i >= 0 {
+				v = src[i] << r
+				if r != 0 && i-1 >= 0 {
+					v |= src[i-1] >> (64 - r)
+				}
+			}
+		}
+		dst[w] = v
+	}
+	if fill {
+		if s > 0 {
+			setRange(dst, clamp(b.width-s, 0, b.width), b.width)
+		} else if s < 0 {
+			setRange(dst, 0, clamp(-s, 0, b.width))
+		}
+	}
+	dst[len(dst)-1] &= b.tailMask()
+}
+
+// probe combines, for every offset, the image read at (x+dx, y+dy): with
+// and=true a pixel is kept only if all reads are set (erosion), otherwise if
+// any is set (dilation). outside is the value of pixels beyond the border.
+func (b *bitmap) probe(offsets []Point, and bool, outside bool) *bitmap {
+	out := newBitmap(b.width, b.height)
+	if b.words == 0 {
+		return out
+	}
+	full := make([]uint64, b.words)
+	if outside {
+		setRange(full, 0, b.width)
+	}
+	empty := make([]uint64, b.words)
+	shifted := make([]uint64, b.words)
+	for y := 0; y < b.height; y++ {
+		row := out.rows[y]
+		if and {
+			setRange(row, 0, b.width)
+		}
+		for _, o := range offsets {
+			sy := y + o.Y
+			if sy < 0 || sy >= b.height {
+				if outside {
+					copy(shifted, full)
+				} else {
+					copy(shifted, empty)
+				}
+			} else {
+				b.shiftRow(b.rows[sy], shifted, o.X, outside)
+			}
+			for w := range row {
+				if and {
+					row[w] &= shifted[w]
+				} else {
+					row[w] |= shifted[w]
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (b *bitmap) erode(se StructuringElement) *bitmap {
+	return b.probe(se.offsets(), true, false)
+}
+
+func (b *bitmap) dilate(se StructuringElement) *bitmap {
+	// Dilation uses the reflected element
+	offsets := se.offsets()
+	for i := range offsets {
+		offsets[i] = Point{-offsets[i].X, -offsets[i].Y}
+	}
+	return b.probe(offsets, false, false)
+}
+
+func (b *bitmap) complement() *bitmap {
+	out := newBitmap(b.width, b.height)
+	for y := range b.rows {
+		for w, v := range b.rows[y] {
+			out.rows[y][w] = ^v
+		}
+		if b.words > 0 {
+			out.rows[y][b.words-1] &= b.tailMask()
+		}
+	}
+	return out
+}
+
+// andNot returns the pixels set in b and not in c.
+func (b *bitmap) andNot(c *bitmap) *bitmap {
+	out := newBitmap(b.width, b.height)
+	for y := range b.rows {
+		for w := range b.rows[y] {
+			out.rows[y][w] = b.rows[y][w] &^ c.rows[y][w]
+		}
+	}
+	return out
+}
+
+func (b *bitmap) and(c *bitmap) *bitmap {
+	out := newBitmap(b.width, b.height)
+	for y := range b.rows {
+		for w := range b.rows[y] {
+			out.rows[y][w] = b.rows[y][w] & c.rows[y][w]
+		}
+	}
+	return out
+}
+
+// count returns the number of set pixels.
+func (b *bitmap) count() int {
+	n := 0
+	for y := range b.rows {
+		for _, v := range b.rows[y] {
+			n += bits.OnesCount64(v)
+		}
+	}
+	return n
+}
+
+func (pbm *PBM) bits() *bitmap {
+	return packBits(pbm.data, pbm.width, pbm.height)
+}
+
+// Black (true) pixels are the foreground in all the operations below, and
+// pixels outside the image count as white.
+
+// Erode shrinks the black regions: a pixel stays black only if the element
+// placed on it fits entirely in black pixels.
+func (pbm *PBM) Erode(se StructuringElement) {
+	pbm.data = storeGrid(pbm.data, pbm.bits().erode(se).unpack())
+}
+
+// Dilate grows the black regions by the element.
+func (pbm *PBM) Dilate(se StructuringElement) {
+	pbm.data = storeGrid(pbm.data, pbm.bits().dilate(se).unpack())
+}
+
+// Open erodes then dilates, removing black details smaller than the element.
+func (pbm *PBM) Open(se StructuringElement) {
+	pbm.data = storeGrid(pbm.data, pbm.bits().erode(se).dilate(se).unpack())
+}
+
+// Close dilates then erodes, filling white gaps smaller than the element.
+func (pbm *PBM) Close(se StructuringElement) {
+	pbm.data = storeGrid(pbm.data, pbm.bits().dilate(se).erode(se).unpack())
+}
+
+// HitOrMiss keeps the pixels where hit fits in the black pixels and miss fits
+// in the white pixels. It finds patterns such as corners or isolated points.
+func (pbm *PBM) HitOrMiss(hit, miss StructuringElement) {
+	b := pbm.bits()
+	fg := b.erode(hit)
+	bg := b.complement().probe(miss.offsets(), true, true)
+	pbm.data = storeGrid(pbm.data, fg.and(bg).unpack())
+}
+
+// MorphGradient keeps the outline of the black regions: the pixels added by a
+// dilation or removed by an erosion.
+func (pbm *PBM) MorphGradient(se StructuringElement) {
+	b := pbm.bits()
+	pbm.data = storeGrid(pbm.data, b.dilate(se).andNot(b.erode(se)).unpack())
+}
+
+// TopHat (white top-hat) keeps the black details removed by an opening.
+func (pbm *PBM) TopHat(se StructuringElement) {
+	b := pbm.bits()
+	pbm.data = storeGrid(pbm.data, b.andNot(b.erode(se).dilate(se)).unpack())
+}
+
+// BlackTopHat keeps the white gaps filled by a closing.
+func (pbm *PBM) BlackTopHat(se StructuringElement) {
+	b := pbm.bits()
+	pbm.data = storeGrid(pbm.data, b.dilate(se).erode(se).andNot(b).unpack())
+}
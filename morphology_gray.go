@@ -0,0 +1,229 @@
+package Netpbm
+
+import "fmt"
+
+// GrayElement is a structuring element for grayscale morphology. A flat
+// element only has a shape; a non-flat one also adds a height to each cell.
+type GrayElement struct {
+	Shape StructuringElement
+	// Heights, indexed [y][x] like the shape, or nil for a flat element.
+	Heights [][]int
+}
+
+// FlatElement returns a grayscale element with the given shape and no height.
+func FlatElement(se StructuringElement) GrayElement {
+	return GrayElement{Shape: se}
+}
+
+// NewGrayElement returns a non-flat element. heights must have the size of
+// the shape.
+func NewGrayElement(se StructuringElement, heights [][]int) (GrayElement, error) {
+	if len(heights) != se.Height {
+		return GrayElement{}, fmt.Errorf("heights need %d rows, got %d", se.Height, len(heights))
+	}
+	h := newGrid[int](se.Width, se.Height)
+	for y := range heights {
+		if len(heights[y]) != se.Width {
+			return GrayElement{}, fmt.Errorf("heights need %d columns, got %d", se.Width, len(heights[y]))
+		}
+		copy(h[y], heights[y])
+	}
+	return GrayElement{Shape: se, Heights: h}, nil
+}
+
+// grayOffset is a set cell of a grayscale element relative to its origin.
+type grayOffset struct {
+	dx, dy, height int
+}
+
+func (ge GrayElement) offsets() []grayOffset {
+	se := ge.Shape
+	var offsets []grayOffset
+	for y := 0; y < se.Height; y++ {
+		for x := 0; x < se.Width; x++ {
+			if !se.cells[y][x] {
+				continue
+			}
+			h := 0
+			if ge.Heights != nil {
+				h = ge.Heights[y][x]
+			}
+			offsets = append(offsets, grayOffset{x - se.OriginX, y - se.OriginY, h})
+		}
+	}
+	return offsets
+}
+
+// grayErode computes min over the element of f(x+dx, y+dy) - height, without
+// clamping the results. Pixels outside the image are ignored; a pixel with no
+// neighbour inside the image gets max.
+func grayErode(data [][]int, width, height, max int, ge GrayElement) [][]int {
+	offsets := ge.offsets()
+	out := newGrid[int](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v, found := max, false
+			for _, o := range offsets {
+				sx, sy := x+o.dx, y+o.dy
+				if sx < 0 || sy < 0 || sx >= width || sy >= height {
+					continue
+				}
+				if s := data[sy][sx] - o.height; !found || s < v {
+					v, found = s, true
+				}
+			}
+			out[y][x] = v
+		}
+	}
+	return out
+}
+
+// grayDilate computes max over the element of f(x-dx, y-dy) + height, using
+// the reflected element, without clamping the results. Pixels outside the
+// image are ignored; a pixel with no neighbour inside the image gets 0.
+func grayDilate(data [][]int, width, height int, ge GrayElement) [][]int {
+	offsets := ge.offsets()
+	out := newGrid[int](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v, found := 0, false
+			for _, o := range offsets {
+				sx, sy := x-o.dx, y-o.dy
+				if sx < 0 || sy < 0 || sx >= width || sy >= height {
+					continue
+				}
+				if s := data[sy][sx] + o.height; !found || s > v {
+					v, found = s, true
+				}
+			}
+			out[y][x] = v
+		}
+	}
+	return out
+}
+
+// intPlane copies samples into a plane of ints, so that non-flat elements can
+// go below 0 or above max between two steps.
+func (pgm *PGM) intPlane() [][]int {
+	out := newGrid[int](pgm.width, pgm.height)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = int(pgm.data[y][x])
+		}
+	}
+	return out
+}
+
+// storeIntPlane clamps a plane of ints to [0, max] and stores it as the image.
+func (pgm *PGM) storeIntPlane(plane [][]int) {
+	out := newGrid[uint16](pgm.width, pgm.height)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = uint16(clamp(plane[y][x], 0, pgm.max))
+		}
+	}
+	pgm.data = storeGrid(pgm.data, out)
+}
+
+// opened returns the unclamped opening of the image, which never exceeds it.
+func (pgm *PGM) opened(ge GrayElement) [][]int {
+	return grayDilate(grayErode(pgm.intPlane(), pgm.width, pgm.height, pgm.max, ge), pgm.width, pgm.height, ge)
+}
+
+// closed returns the unclamped closing of the image, which is never below it.
+func (pgm *PGM) closed(ge GrayElement) [][]int {
+	return grayErode(grayDilate(pgm.intPlane(), pgm.width, pgm.height, ge), pgm.width, pgm.height, pgm.max, ge)
+}
+
+// Erode replaces each pixel by the minimum of its neighbourhood under the
+// element, minus the element heights. Bright regions shrink.
+func (pgm *PGM) Erode(ge GrayElement) {
+	pgm.storeIntPlane(grayErode(pgm.intPlane(), pgm.width, pgm.height, pgm.max, ge))
+}
+
+// Dilate replaces each pixel by the maximum of its neighbourhood under the
+// element, plus the element heights. Bright regions grow.
+func (pgm *PGM) Dilate(ge GrayElement) {
+	pgm.storeIntPlane(grayDilate(pgm.intPlane(), pgm.width, pgm.height, ge))
+}
+
+// Open erodes then dilates, removing bright details smaller than the element.
+func (pgm *PGM) Open(ge GrayElement) {
+	pgm.storeIntPlane(pgm.opened(ge))
+}
+
+// Close dilates then erodes, removing dark details smaller than the element.
+func (pgm *PGM) Close(ge GrayElement) {
+	pgm.storeIntPlane(pgm.closed(ge))
+}
+
+// TopHat (white top-hat) keeps the bright details removed by an opening. With
+// an element larger than the details, it subtracts an uneven background.
+func (pgm *PGM) TopHat(ge GrayElement) {
+	opened := pgm.opened(ge)
+	for y := range opened {
+		for x := range opened[y] {
+			opened[y][x] = int(pgm.data[y][x]) - opened[y][x]
+		}
+	}
+	pgm.storeIntPlane(opened)
+}
+
+// BlackTopHat keeps the dark details removed by a closing, as bright values.
+func (pgm *PGM) BlackTopHat(ge GrayElement) {
+	closed := pgm.closed(ge)
+	for y := range closed {
+		for x := range closed[y] {
+			closed[y][x] -= int(pgm.data[y][x])
+		}
+	}
+	pgm.storeIntPlane(closed)
+}
+
+// Reconstruct performs morphological reconstruction by dilation: the image,
+// used as a marker, is dilated repeatedly under the mask until it stops
+// changing. The marker must be below the mask everywhere; it is clipped to
+// the mask first. Only the regions of the mask touched by the marker are
+// rebuilt, which extracts regional maxima or fills holes.
+func (pgm *PGM) Reconstruct(mask *PGM, connectivity Connectivity) error {
+	if mask.width != pgm.width || mask.height != pgm.height {
+		return fmt.Errorf("mask size does not match the image")
+	}
+	w, h := pgm.width, pgm.height
+	neighbors := connectivity.offsets()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if pgm.data[y][x] > mask.data[y][x] {
+				pgm.data[y][x] = mask.data[y][x]
+			}
+		}
+	}
+
+	// Queue-based propagation (Vincent's algorithm)
+	var queue []Point
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			queue = append(queue, Point{x, y})
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		v := pgm.data[p.Y][p.X]
+		for _, n := range neighbors {
+			nx, ny := p.X+n.X, p.Y+n.Y
+			if nx < 0 || ny < 0 || nx >= w || ny >= h {
+				continue
+			}
+			if pgm.data[ny][nx] < v && pgm.data[ny][nx] < mask.data[ny][nx] {
+				nv := v
+				if mask.data[ny][nx] < nv {
+					nv = mask.data[ny][nx]
+				}
+				pgm.data[ny][nx] = nv
+				queue = append(queue, Point{nx, ny})
+			}
+		}
+	}
+	return nil
+}
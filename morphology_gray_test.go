@@ -0,0 +1,108 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func flatPGM(width, height int, value uint16) *PGM {
+	return newTestPGM(width, height, func(x, y int) uint16 { return value })
+}
+
+func nonFlatElement(t *testing.T, height int) GrayElement {
+	heights := [][]int{{height, height, height}, {height, height, height}, {height, height, height}}
+	ge, err := NewGrayElement(SquareElement(3), heights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ge
+}
+
+func TestGrayErodeDilateFlat(t *testing.T) {
+	ge := FlatElement(CrossElement(3))
+	pgm := newTestPGM(5, 1, func(x, y int) uint16 { return []uint16{10, 50, 30, 90, 20}[x] })
+	pgm.Erode(ge)
+	if want := []uint16{10, 10, 30, 20, 20}; !reflect.DeepEqual(pgm.data[0], want) {
+		t.Fatalf("erode: got %v, want %v", pgm.data[0], want)
+	}
+	pgm = newTestPGM(5, 1, func(x, y int) uint16 { return []uint16{10, 50, 30, 90, 20}[x] })
+	pgm.Dilate(ge)
+	if want := []uint16{50, 50, 90, 90, 90}; !reflect.DeepEqual(pgm.data[0], want) {
+		t.Fatalf("dilate: got %v, want %v", pgm.data[0], want)
+	}
+}
+
+func TestTopHatNonFlat(t *testing.T) {
+	ge := nonFlatElement(t, 20)
+
+	pgm := flatPGM(5, 5, 10)
+	pgm.TopHat(ge)
+	if got := pgm.data[2][2]; got != 0 {
+		t.Fatalf("top-hat of a flat image: got %d, want 0", got)
+	}
+
+	pgm = flatPGM(5, 5, 250)
+	pgm.BlackTopHat(ge)
+	if got := pgm.data[2][2]; got != 0 {
+		t.Fatalf("black top-hat of a flat image: got %d, want 0", got)
+	}
+
+	pgm = flatPGM(5, 5, 10)
+	pgm.data[2][2] = 100
+	pgm.TopHat(ge)
+	if got := pgm.data[2][2]; got != 90 {
+		t.Fatalf("top-hat of a peak: got %d, want 90", got)
+	}
+}
+
+func TestOpenNonFlat(t *testing.T) {
+	pgm := flatPGM(5, 5, 10)
+	pgm.Open(nonFlatElement(t, 20))
+	if got := pgm.data[2][2]; got != 10 {
+		t.Fatalf("opening of a flat image: got %d, want 10", got)
+	}
+	pgm.Close(nonFlatElement(t, 20))
+	if got := pgm.data[2][2]; got != 10 {
+		t.Fatalf("closing of a flat image: got %d, want 10", got)
+	}
+	if _, err := NewGrayElement(SquareElement(3), [][]int{{0}}); err == nil {
+		t.Fatal("heights of the wrong size accepted")
+	}
+}
+
+func TestReconstructKeepsMarkedPeaks(t *testing.T) {
+	mask := newTestPGM(7, 1, func(x, y int) uint16 { return []uint16{0, 80, 80, 0, 60, 60, 0}[x] })
+	marker := flatPGM(7, 1, 0)
+	marker.data[0][1] = 255
+	if err := marker.Reconstruct(mask, Connectivity4); err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{0, 80, 80, 0, 0, 0, 0}; !reflect.DeepEqual(marker.data[0], want) {
+		t.Fatalf("got %v, want %v", marker.data[0], want)
+	}
+	if err := marker.Reconstruct(flatPGM(2, 2, 0), Connectivity8); err == nil {
+		t.Fatal("mask of the wrong size accepted")
+	}
+}
+
+func TestReconstructConnectivity(t *testing.T) {
+	mask := newTestPGM(2, 2, func(x, y int) uint16 {
+		if x == y {
+			return 100
+		}
+		return 0
+	})
+	for _, tt := range []struct {
+		c    Connectivity
+		want uint16
+	}{{Connectivity4, 0}, {Connectivity8, 100}} {
+		marker := flatPGM(2, 2, 0)
+		marker.data[0][0] = 100
+		if err := marker.Reconstruct(mask, tt.c); err != nil {
+			t.Fatal(err)
+		}
+		if got := marker.data[1][1]; got != tt.want {
+			t.Errorf("connectivity %d: diagonal pixel %d, want %d", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// patternPBM returns a width x height image with a scattered deterministic
+// pattern, so that black runs cross the 64-pixel word boundaries.
+func patternPBM(width, height int) *PBM {
+	data := newGrid[bool](width, height)
+	for y := range data {
+		for x := range data[y] {
+			data[y][x] = (x*x+3*y*x+7*y)%5 < 2
+		}
+	}
+	return &PBM{data: data, width: width, height: height, magicNumber: "P1"}
+}
+
+// naiveProbe is the pixel-by-pixel definition of erosion (all) and dilation
+// (any), with white outside the image.
+func naiveProbe(data [][]bool, width, height int, se StructuringElement, erode bool) [][]bool {
+	at := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < width && y < height && data[y][x]
+	}
+	out := newGrid[bool](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			result := erode
+			for _, o := range se.offsets() {
+				if erode {
+					result = result && at(x+o.X, y+o.Y)
+				} else {
+					result = result || at(x-o.X, y-o.Y)
+				}
+			}
+			out[y][x] = result
+		}
+	}
+	return out
+}
+
+func asymmetricElement(t *testing.T) StructuringElement {
+	t.Helper()
+	se, err := NewStructuringElement([][]bool{
+		{true, false, false, true},
+		{false, true, true, true},
+	}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return se
+}
+
+func TestMorphologyMatchesNaiveAcrossWords(t *testing.T) {
+	elements := map[string]StructuringElement{
+		"square": SquareElement(3),
+		"cross":  CrossElement(5),
+		"disk":   DiskElement(2),
+		"asym":   asymmetricElement(t),
+	}
+	for _, width := range []int{63, 64, 65, 130} {
+		for name, se := range elements {
+			src := patternPBM(width, 5)
+			eroded := patternPBM(width, 5)
+			eroded.Erode(se)
+			if want := naiveProbe(src.data, width, 5, se, true); !reflect.DeepEqual(eroded.data, want) {
+				t.Errorf("erode %s, width %d: differs from the naive version", name, width)
+			}
+			dilated := patternPBM(width, 5)
+			dilated.Dilate(se)
+			if want := naiveProbe(src.data, width, 5, se, false); !reflect.DeepEqual(dilated.data, want) {
+				t.Errorf("dilate %s, width %d: differs from the naive version", name, width)
+			}
+		}
+	}
+}
+
+func TestBitmapPackRoundTrip(t *testing.T) {
+	pbm := patternPBM(130, 3)
+	b := pbm.bits()
+	if !reflect.DeepEqual(b.unpack(), pbm.data) {
+		t.Fatal("unpack(pack(x)) differs from x")
+	}
+	want := 0
+	for y := range pbm.data {
+		for _, black := range pbm.data[y] {
+			if black {
+				want++
+			}
+		}
+	}
+	if got := b.count(); got != want {
+		t.Fatalf("count %d, want %d", got, want)
+	}
+	// The complement must not set the padding bits past the width.
+	if got := b.complement().count(); got != 130*3-want {
+		t.Fatalf("complement count %d, want %d", got, 130*3-want)
+	}
+}
+
+func TestShiftAcrossWordBoundary(t *testing.T) {
+	left, _ := NewStructuringElement([][]bool{{true, false, false}}, 2, 0)
+	right, _ := NewStructuringElement([][]bool{{false, false, true}}, 0, 0)
+	for _, tt := range []struct {
+		se       StructuringElement
+		from, to int
+	}{{left, 65, 63}, {right, 62, 64}} {
+		row := make([]bool, 130)
+		row[tt.from] = true
+		pbm := &PBM{data: [][]bool{row}, width: 130, height: 1, magicNumber: "P1"}
+		pbm.Dilate(tt.se)
+		if !pbm.data[0][tt.to] || pbm.bits().count() != 1 {
+			t.Errorf("pixel %d should move to %d only", tt.from, tt.to)
+		}
+	}
+}
+
+func TestOpenCloseAndHitOrMiss(t *testing.T) {
+	pbm := newTestPBM(
+		"#.......",
+		"...###..",
+		"...###..",
+		"...###..",
+	)
+	pbm.Open(SquareElement(3))
+	if want := []string{"........", "...###..", "...###..", "...###.."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("open: got %v", pbmRows(pbm))
+	}
+
+	gap := newTestPBM(
+		"###.###",
+		"###.###",
+		"###.###",
+	)
+	gap.Close(SquareElement(3))
+	if gap.data[1][3] != true {
+		t.Fatalf("close did not fill the gap: %v", pbmRows(gap))
+	}
+
+	isolated := newTestPBM(
+		".....",
+		".#...",
+		".....",
+		"...##",
+	)
+	hit, _ := NewStructuringElement([][]bool{{true}}, 0, 0)
+	miss := SquareElement(3)
+	miss.cells[1][1] = false
+	isolated.HitOrMiss(hit, miss)
+	if want := []string{".....", ".#...", ".....", "....."}; !reflect.DeepEqual(pbmRows(isolated), want) {
+		t.Fatalf("hit-or-miss: got %v", pbmRows(isolated))
+	}
+}
+
+func TestMorphGradientAndTopHats(t *testing.T) {
+	square := func() *PBM {
+		return newTestPBM(".....", ".###.", ".###.", ".###.", ".....")
+	}
+	g := square()
+	g.MorphGradient(CrossElement(3))
+	if g.data[2][2] || !g.data[1][1] || !g.data[0][2] {
+		t.Fatalf("gradient: got %v", pbmRows(g))
+	}
+	top := square()
+	top.TopHat(SquareElement(3))
+	if top.bits().count() != 0 {
+		t.Fatalf("top-hat of a 3x3 square by a 3x3 square: got %v", pbmRows(top))
+	}
+	black := newTestPBM("###", "#.#", "###")
+	black.BlackTopHat(SquareElement(3))
+	if want := []string{"...", ".#.", "..."}; !reflect.DeepEqual(pbmRows(black), want) {
+		t.Fatalf("black top-hat: got %v", pbmRows(black))
+	}
+}
+
+func TestNewStructuringElementErrors(t *testing.T) {
+	if _, err := NewStructuringElement(nil, 0, 0); err == nil {
+		t.Error("empty element accepted")
+	}
+	if _, err := NewStructuringElement([][]bool{{true}}, 1, 0); err == nil {
+		t.Error("origin outside accepted")
+	}
+	if _, err := NewStructuringElement([][]bool{{true, true}, {true}}, 0, 0); err == nil {
+		t.Error("ragged rows accepted")
+	}
+}
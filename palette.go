@@ -0,0 +1,266 @@
+package Netpbm
+
+import "fmt"
+
+// Paletted is an indexed-color image: every pixel is an index into a palette
+// of at most 256 colors.
+type Paletted struct {
+	data          [][]uint8
+	width, height int
+	palette       []Pixel
+	max           int
+}
+
+// ColorMetric selects how the distance between two colors is measured when
+// looking for the nearest palette entry.
+type ColorMetric int
+
+const (
+	// MetricRGB uses the squared Euclidean distance in RGB space.
+	MetricRGB ColorMetric = iota
+	// MetricLab uses the squared Euclidean distance in CIE Lab space (Delta E 1976).
+	MetricLab
+)
+
+// NewPaletted creates a paletted image filled with index 0.
+func NewPaletted(width, height int, palette []Pixel, max int) (*Paletted, error) {
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("invalid image size: %dx%d", width, height)
+	}
+	if len(palette) == 0 || len(palette) > 256 {
+		return nil, fmt.Errorf("invalid palette size: %d", len(palette))
+	}
+	data := make([][]uint8, height)
+	for y := range data {
+		data[y] = make([]uint8, width)
+	}
+	pal := make([]Pixel, len(palette))
+	copy(pal, palette)
+	return &Paletted{data: data, width: width, height: height, palette: pal, max: max}, nil
+}
+
+// ToPaletted converts the PPM image to a paletted image without loss. It fails
+// if the image uses more than 256 distinct colors.
+func (ppm *PPM) ToPaletted() (*Paletted, error) {
+	indices := make(map[Pixel]uint8)
+	var palette []Pixel
+	data := make([][]uint8, ppm.height)
+	for y := 0; y < ppm.height; y++ {
+		data[y] = make([]uint8, ppm.width)
+		for x := 0; x < ppm.width; x++ {
+			color := ppm.data[y][x]
+			index, ok := indices[color]
+			if !ok {
+				if len(palette) == 256 {
+					return nil, fmt.Errorf("too many colors for a palette")
+				}
+				index = uint8(len(palette))
+				indices[color] = index
+				palette = append(palette, color)
+			}
+			data[y][x] = index
+		}
+	}
+	if len(palette) == 0 {
+		palette = []Pixel{{}}
+	}
+	return &Paletted{data: data, width: ppm.width, height: ppm.height, palette: palette, max: ppm.max}, nil
+}
+
+// Remap converts the PPM image to a paletted image using a fixed palette,
+// replacing every pixel by the nearest palette color.
+func (ppm *PPM) Remap(palette []Pixel, metric ColorMetric) (*Paletted, error) {
+	pal, err := NewPaletted(ppm.width, ppm.height, palette, ppm.max)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newPaletteMatcher(pal.palette, pal.max, metric)
+	for y := 0; y < ppm.height; y++ {
+		for x := 0; x < ppm.width; x++ {
+			pal.data[y][x] = matcher.nearest(ppm.data[y][x])
+		}
+	}
+	return pal, nil
+}
+
+// Size returns the width and height of the image.
+func (p *Paletted) Size() (int, int) {
+	return p.width, p.height
+}
+
+// IndexAt returns the palette index of the pixel at (x, y).
+func (p *Paletted) IndexAt(x, y int) uint8 {
+	return p.data[y][x]
+}
+
+// SetIndex sets the palette index of the pixel at (x, y).
+func (p *Paletted) SetIndex(x, y int, index uint8) error {
+	if int(index) >= len(p.palette) {
+		return fmt.Errorf("palette index %d out of range", index)
+	}
+	p.data[y][x] = index
+	return nil
+}
+
+// At returns the color of the pixel at (x, y).
+func (p *Paletted) At(x, y int) Pixel {
+	return p.palette[p.data[y][x]]
+}
+
+// Palette returns a copy of the palette.
+func (p *Paletted) Palette() []Pixel {
+	pal := make([]Pixel, len(p.palette))
+	copy(pal, p.palette)
+	return pal
+}
+
+// SetPaletteColor replaces the color of a palette entry. Every pixel using that
+// entry changes color.
+func (p *Paletted) SetPaletteColor(index int, color Pixel) error {
+	if index < 0 || index >= len(p.palette) {
+		return fmt.Errorf("palette index %d out of range", index)
+	}
+	p.palette[index] = color
+	return nil
+}
+
+// AddColor appends a color to the palette and returns its index.
+func (p *Paletted) AddColor(color Pixel) (uint8, error) {
+	if len(p.palette) == 256 {
+		return 0, fmt.Errorf("palette is full")
+	}
+	p.palette = append(p.palette, color)
+	return uint8(len(p.palette) - 1), nil
+}
+
+// Compact removes unused and duplicate palette entries and renumbers the pixels.
+func (p *Paletted) Compact() {
+	used := make([]bool, len(p.palette))
+	for y := range p.data {
+		for _, index := range p.data[y] {
+			used[index] = true
+		}
+	}
+
+	newIndex := make([]uint8, len(p.palette))
+	seen := make(map[Pixel]uint8)
+	var palette []Pixel
+	for i, color := range p.palette {
+		if !used[i] {
+			continue
+		}
+		if index, ok := seen[color]; ok {
+			newIndex[i] = index
+			continue
+		}
+		newIndex[i] = uint8(len(palette))
+		seen[color] = newIndex[i]
+		palette = append(palette, color)
+	}
+	if len(palette) == 0 {
+		palette = []Pixel{p.palette[0]}
+	}
+
+	for y := range p.data {
+		for x, index := range p.data[y] {
+			p.data[y][x] = newIndex[index]
+		}
+	}
+	p.palette = palette
+}
+
+// Remap replaces the palette with a fixed one, moving every pixel to the entry
+// nearest to its current color.
+func (p *Paletted) Remap(palette []Pixel, metric ColorMetric) error {
+	if len(palette) == 0 || len(palette) > 256 {
+		return fmt.Errorf("invalid palette size: %d", len(palette))
+	}
+	pal := make([]Pixel, len(palette))
+	copy(pal, palette)
+
+	// Only the old palette entries need matching, not every pixel.
+	matcher := newPaletteMatcher(pal, p.max, metric)
+	mapping := make([]uint8, len(p.palette))
+	for i, color := range p.palette {
+		mapping[i] = matcher.nearest(color)
+	}
+	for y := range p.data {
+		for x, index := range p.data[y] {
+			p.data[y][x] = mapping[index]
+		}
+	}
+	p.palette = pal
+	return nil
+}
+
+// ToPPM converts the paletted image back to a PPM image.
+func (p *Paletted) ToPPM() *PPM {
+	data := make([][]Pixel, p.height)
+	for y := range data {
+		data[y] = make([]Pixel, p.width)
+		for x := range data[y] {
+			data[y][x] = p.palette[p.data[y][x]]
+		}
+	}
+	return &PPM{data: data, width: p.width, height: p.height, magicNumber: "P6", max: p.max}
+}
+
+// Save writes the paletted image to a file as a PPM image with the given
+// magic number ("P3" or "P6"). No color is lost.
+func (p *Paletted) Save(filename string, magicNumber string) error {
+	ppm := p.ToPPM()
+	ppm.SetMagicNumber(magicNumber)
+	return ppm.Save(filename)
+}
+
+// paletteMatcher finds the nearest palette entry of a color and caches results.
+type paletteMatcher struct {
+	palette []Pixel
+	lab     []Lab
+	max     int
+	metric  ColorMetric
+	cache   map[Pixel]uint8
+}
+
+func newPaletteMatcher(palette []Pixel, max int, metric ColorMetric) *paletteMatcher {
+	m := &paletteMatcher{palette: palette, max: max, metric: metric, cache: make(map[Pixel]uint8)}
+	if metric == MetricLab {
+		m.lab = make([]Lab, len(palette))
+		for i, color := range palette {
+			m.lab[i] = color.ToLab(max)
+		}
+	}
+	return m
+}
+
+func (m *paletteMatcher) nearest(color Pixel) uint8 {
+	if index, ok := m.cache[color]; ok {
+		return index
+	}
+
+	best := 0
+	bestDist := -1.0
+	if m.metric == MetricLab {
+		lab := color.ToLab(m.max)
+		for i, c := range m.lab {
+			dl, da, db := lab.L-c.L, lab.A-c.A, lab.B-c.B
+			dist := dl*dl + da*da + db*db
+			if bestDist < 0 || dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+	} else {
+		for i, c := range m.palette {
+			dr := float64(color.R) - float64(c.R)
+			dg := float64(color.G) - float64(c.G)
+			db := float64(color.B) - float64(c.B)
+			dist := dr*dr + dg*dg + db*db
+			if bestDist < 0 || dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+	}
+
+	m.cache[color] = uint8(best)
+	return uint8(best)
+}
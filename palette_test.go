@@ -0,0 +1,109 @@
+package Netpbm
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+var testPalette = []Pixel{{0, 0, 0}, {255, 0, 0}, {0, 0, 255}, {255, 255, 255}}
+
+func TestNewPalettedInvalid(t *testing.T) {
+	if _, err := NewPaletted(-1, 2, testPalette, 255); err == nil {
+		t.Fatal("negative width accepted")
+	}
+	if _, err := NewPaletted(2, -1, testPalette, 255); err == nil {
+		t.Fatal("negative height accepted")
+	}
+	if _, err := NewPaletted(2, 2, nil, 255); err == nil {
+		t.Fatal("empty palette accepted")
+	}
+	if _, err := NewPaletted(2, 2, make([]Pixel, 257), 255); err == nil {
+		t.Fatal("palette of 257 colors accepted")
+	}
+	p, err := NewPaletted(0, 0, testPalette, 255)
+	if err != nil || p.width != 0 || p.height != 0 {
+		t.Fatalf("empty image: got %v, %v", p, err)
+	}
+}
+
+func TestToPalettedRoundTrip(t *testing.T) {
+	ppm := newTestPPM(4, 3, func(x, y int) Pixel { return testPalette[(x+y)%3] })
+	p, err := ppm.ToPaletted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Palette()); n != 3 {
+		t.Fatalf("got %d palette entries, want 3", n)
+	}
+	back := p.ToPPM()
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			if back.data[y][x] != ppm.data[y][x] {
+				t.Fatalf("pixel (%d, %d): got %v, want %v", x, y, back.data[y][x], ppm.data[y][x])
+			}
+		}
+	}
+}
+
+func TestToPalettedTooManyColors(t *testing.T) {
+	ppm := newTestPPM(257, 1, func(x, y int) Pixel { return Pixel{uint16(x % 256), uint16(x >> 8), 0} })
+	if _, err := ppm.ToPaletted(); err == nil {
+		t.Fatal("expected an error for 257 colors")
+	}
+}
+
+func TestRemapNearest(t *testing.T) {
+	ppm := newTestPPM(3, 1, func(x, y int) Pixel {
+		return []Pixel{{200, 30, 20}, {10, 20, 240}, {230, 240, 250}}[x]
+	})
+	for _, metric := range []ColorMetric{MetricRGB, MetricLab} {
+		p, err := ppm.Remap(testPalette, metric)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, want := range []uint8{1, 2, 3} {
+			if got := p.IndexAt(x, 0); got != want {
+				t.Fatalf("metric %d, pixel %d: got index %d, want %d", metric, x, got, want)
+			}
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	p, err := NewPaletted(2, 1, []Pixel{{1, 1, 1}, {9, 9, 9}, {5, 5, 5}, {9, 9, 9}}, 255)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetIndex(0, 0, 3)
+	p.SetIndex(1, 0, 1)
+	p.Compact()
+	if n := len(p.Palette()); n != 1 {
+		t.Fatalf("got %d palette entries, want 1", n)
+	}
+	if p.At(0, 0) != (Pixel{9, 9, 9}) || p.At(1, 0) != (Pixel{9, 9, 9}) {
+		t.Fatalf("colors changed: %v %v", p.At(0, 0), p.At(1, 0))
+	}
+}
+
+func TestSetIndexOutOfRange(t *testing.T) {
+	p, _ := NewPaletted(1, 1, testPalette, 255)
+	if err := p.SetIndex(0, 0, 4); err == nil {
+		t.Fatal("index 4 accepted with a palette of 4 colors")
+	}
+}
+
+func TestPalettedSave(t *testing.T) {
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return testPalette[x+2*y] })
+	p, _ := ppm.ToPaletted()
+	filename := filepath.Join(t.TempDir(), "palette.ppm")
+	if err := p.Save(filename, "P3"); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPPM(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.data[1][1] != testPalette[3] {
+		t.Fatalf("got %v, want %v", read.data[1][1], testPalette[3])
+	}
+}
@@ -202,6 +202,43 @@ func (pbm *PBM) Flop() {
 	}
 }
 
+// Rotate90CW rotates the PBM image 90° clockwise.
+func (pbm *PBM) Rotate90CW() {
+	pbm.data = storeGrid(pbm.data, rotate90CW(pbm.data, pbm.width, pbm.height))
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Rotate90CCW rotates the PBM image 90° counterclockwise.
+func (pbm *PBM) Rotate90CCW() {
+	pbm.data = storeGrid(pbm.data, rotate90CCW(pbm.data, pbm.width, pbm.height))
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Rotate180 rotates the PBM image by 180°.
+func (pbm *PBM) Rotate180() {
+	pbm.data = storeGrid(pbm.data, rotate180(pbm.data, pbm.width, pbm.height))
+}
+
+// Rotate270CW rotates the PBM image 270° clockwise, which is the same as
+// Rotate90CCW.
+func (pbm *PBM) Rotate270CW() {
+	pbm.Rotate90CCW()
+}
+
+// Transpose mirrors the PBM image across its main diagonal (top-left to
+// bottom-right).
+func (pbm *PBM) Transpose() {
+	pbm.data = storeGrid(pbm.data, transpose(pbm.data, pbm.width, pbm.height))
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
+// Transverse mirrors the PBM image across its anti-diagonal (top-right to
+// bottom-left).
+func (pbm *PBM) Transverse() {
+	pbm.data = storeGrid(pbm.data, transverse(pbm.data, pbm.width, pbm.height))
+	pbm.width, pbm.height = pbm.height, pbm.width
+}
+
 func (pbm *PBM) SetMagicNumber(magicNumber string) {
 	pbm.magicNumber = magicNumber
 }
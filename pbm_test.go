@@ -0,0 +1,32 @@
+package Netpbm
+
+import "strings"
+
+// newTestPBM builds a P1 image from rows where '#' marks a black pixel.
+func newTestPBM(rows ...string) *PBM {
+	data := make([][]bool, len(rows))
+	for y, row := range rows {
+		data[y] = make([]bool, len(row))
+		for x, c := range row {
+			data[y][x] = c == '#'
+		}
+	}
+	return &PBM{data: data, width: len(rows[0]), height: len(rows), magicNumber: "P1"}
+}
+
+// pbmRows renders the image back to the notation used by newTestPBM.
+func pbmRows(pbm *PBM) []string {
+	rows := make([]string, pbm.height)
+	for y := range pbm.data {
+		var b strings.Builder
+		for _, black := range pbm.data[y] {
+			if black {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		rows[y] = b.String()
+	}
+	return rows
+}
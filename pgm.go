@@ -9,7 +9,7 @@ import (
 )
 
 type PGM struct {
-	data          [][]uint8
+	data          [][]uint16
 	width, height int
 	magicNumber   string
 	max           int
@@ -55,9 +55,9 @@ func ReadPGM(filename string) (*PGM, error) {
 			}
 
 			// Initialiser la matrice de données
-			pgmIn.data = make([][]uint8, pgmIn.height)
+			pgmIn.data = make([][]uint16, pgmIn.height)
 			for j := range pgmIn.data {
-				pgmIn.data[j] = make([]uint8, pgmIn.width)
+				pgmIn.data[j] = make([]uint16, pgmIn.width)
 			}
 		}
 		if i == 2 {
@@ -80,7 +80,7 @@ func ReadPGM(filename string) (*PGM, error) {
 					if err != nil {
 						return nil, fmt.Errorf("Valeur de pixel invalide") // On créé une erreur
 					}
-					pgmIn.data[i-3][j] = uint8(val)
+					pgmIn.data[i-3][j] = uint16(val)
 				}
 			} else if pgmIn.magicNumber == "P5" {
 				x, y := 0, 0
@@ -91,7 +91,7 @@ func ReadPGM(filename string) (*PGM, error) {
 						x = 0
 						y++
 					}
-					pgmIn.data[y][x] = uint8(asciiCode)
+					pgmIn.data[y][x] = uint16(asciiCode)
 					x++
 				}
 			} else {
@@ -109,10 +109,10 @@ func (pgm *PGM) Size() (int, int) {
 	return pgm.width, pgm.height
 }
 
-func (pgm *PGM) At(x, y int) uint8 {
+func (pgm *PGM) At(x, y int) uint16 {
 	return pgm.data[x][y]
 }
-func (pgm *PGM) Set(x, y int, value uint8) {
+func (pgm *PGM) Set(x, y int, value uint16) {
 	pgm.data[x][y] = value
 }
 func (pgm *PGM) Save(filename string) error {
@@ -155,7 +155,7 @@ func (pgm *PGM) Save(filename string) error {
 		// Write binary data for P5 format
 		for _, row := range pgm.data {
 			for _, pixel := range row {
-				err = writer.WriteByte(pixel)
+				err = writeSample(writer, pixel, pgm.max)
 				if err != nil {
 					return err
 				}
@@ -169,7 +169,7 @@ func (pgm *PGM) Save(filename string) error {
 }
 
 func (pgm *PGM) Invert() {
-	maxVal := uint8(pgm.max)
+	maxVal := uint16(pgm.max)
 	for y := range pgm.data {
 		for x := range pgm.data[y] {
 			pgm.data[y][x] = maxVal - pgm.data[y][x]
@@ -201,11 +201,37 @@ func (pgm *PGM) SetMagicNumber(magicNumber string) {
 	pgm.magicNumber = magicNumber
 }
 
+// SetMaxValue sets the max value of the PGM image, rescaling the samples.
+// It used to clip the samples instead; use Clamp for that behavior. It is a
+// thin wrapper around Rescale that ignores invalid max values such as 0; call
+// Rescale directly to get the error.
 func (pgm *PGM) SetMaxValue(maxValue uint8) {
-	// Mettre à jour la valeur maximale
-	pgm.max = int(maxValue)
+	_ = pgm.Rescale(int(maxValue))
+}
 
-	// Ajuster les valeurs des pixels pour s'assurer qu'elles ne dépassent pas la nouvelle valeur maximale
+// Rescale changes the max value of the PGM image and maps every sample
+// proportionally to the new range, rounding to the nearest value.
+func (pgm *PGM) Rescale(newMax int) error {
+	if err := checkMaxValue(newMax); err != nil {
+		return err
+	}
+	for i := range pgm.data {
+		for j := range pgm.data[i] {
+			pgm.data[i][j] = rescaleSample(pgm.data[i][j], pgm.max, newMax)
+		}
+	}
+	pgm.max = newMax
+	return nil
+}
+
+// Clamp changes the max value of the PGM image and clips the samples that
+// exceed it, leaving the others untouched.
+func (pgm *PGM) Clamp(newMax int) error {
+	if err := checkMaxValue(newMax); err != nil {
+		return err
+	}
+	// Clip the samples that exceed the new max value
+	maxValue := uint16(newMax)
 	for i := range pgm.data {
 		for j := range pgm.data[i] {
 			if pgm.data[i][j] > maxValue {
@@ -213,24 +239,75 @@ func (pgm *PGM) SetMaxValue(maxValue uint8) {
 			}
 		}
 	}
+	pgm.max = newMax
+	return nil
 }
 
-func (pgm *PGM) Rotate90CW() {
-	// Créer une nouvelle matrice de la taille de l'image pivotée
-	newData := make([][]uint8, pgm.width)
-	for i := range newData {
-		newData[i] = make([]uint8, pgm.height)
+// checkMaxValue validates a max value. The Netpbm formats allow 1 to 65535.
+func checkMaxValue(maxValue int) error {
+	if maxValue < 1 || maxValue > 65535 {
+		return fmt.Errorf("max value must be between 1 and 65535: %d", maxValue)
+	}
+	return nil
+}
+
+// writeSample writes a binary sample: one byte when max is below 256, two
+// bytes (most significant first) otherwise, as the Netpbm formats require.
+func writeSample(writer *bufio.Writer, value uint16, max int) error {
+	if max < 256 {
+		return writer.WriteByte(byte(value))
 	}
+	_, err := writer.Write([]byte{byte(value >> 8), byte(value)})
+	return err
+}
 
-	// Effectuer la rotation
-	for i := 0; i < pgm.height; i++ {
-		for j := 0; j < pgm.width; j++ {
-			newData[j][pgm.height-1-i] = pgm.data[i][j]
-		}
+// rescaleSample maps a sample from [0, oldMax] to [0, newMax], rounding to the
+// nearest value. Samples above oldMax are treated as oldMax.
+func rescaleSample(value uint16, oldMax, newMax int) uint16 {
+	if oldMax <= 0 {
+		return 0
 	}
+	v := int(value)
+	if v > oldMax {
+		v = oldMax
+	}
+	return uint16((v*newMax + oldMax/2) / oldMax)
+}
+
+// Rotate90CW rotates the PGM image 90° clockwise.
+func (pgm *PGM) Rotate90CW() {
+	pgm.data = storeGrid(pgm.data, rotate90CW(pgm.data, pgm.width, pgm.height))
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Rotate90CCW rotates the PGM image 90° counterclockwise.
+func (pgm *PGM) Rotate90CCW() {
+	pgm.data = storeGrid(pgm.data, rotate90CCW(pgm.data, pgm.width, pgm.height))
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
+
+// Rotate180 rotates the PGM image by 180°.
+func (pgm *PGM) Rotate180() {
+	pgm.data = storeGrid(pgm.data, rotate180(pgm.data, pgm.width, pgm.height))
+}
+
+// Rotate270CW rotates the PGM image 270° clockwise, which is the same as
+// Rotate90CCW.
+func (pgm *PGM) Rotate270CW() {
+	pgm.Rotate90CCW()
+}
+
+// Transpose mirrors the PGM image across its main diagonal (top-left to
+// bottom-right).
+func (pgm *PGM) Transpose() {
+	pgm.data = storeGrid(pgm.data, transpose(pgm.data, pgm.width, pgm.height))
+	pgm.width, pgm.height = pgm.height, pgm.width
+}
 
-	// Mettre à jour la structure PGM
-	pgm.data = newData
+// Transverse mirrors the PGM image across its anti-diagonal (top-right to
+// bottom-left).
+func (pgm *PGM) Transverse() {
+	pgm.data = storeGrid(pgm.data, transverse(pgm.data, pgm.width, pgm.height))
 	pgm.width, pgm.height = pgm.height, pgm.width
 }
 
@@ -246,7 +323,7 @@ func (pgm *PGM) ToPBM() *PBM {
 	for y := range pbm.data {
 		pbm.data[y] = make([]bool, pbm.width)
 		for x := range pbm.data[y] {
-			pbm.data[y][x] = pgm.data[y][x] > uint8(threshold)
+			pbm.data[y][x] = pgm.data[y][x] > uint16(threshold)
 		}
 	}
 
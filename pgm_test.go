@@ -0,0 +1,118 @@
+package Netpbm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestPGM returns a P2 image with maxval 255 whose samples are given by fill.
+func newTestPGM(width, height int, fill func(x, y int) uint16) *PGM {
+	data := make([][]uint16, height)
+	for y := range data {
+		data[y] = make([]uint16, width)
+		for x := range data[y] {
+			data[y][x] = fill(x, y)
+		}
+	}
+	return &PGM{data: data, width: width, height: height, magicNumber: "P2", max: 255}
+}
+
+func TestPGMRescaleRoundsToNearest(t *testing.T) {
+	samples := []uint16{0, 1, 2, 3, 50, 128, 200, 255}
+	pgm := newTestPGM(len(samples), 1, func(x, y int) uint16 { return samples[x] })
+	if err := pgm.Rescale(100); err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{0, 0, 1, 1, 20, 50, 78, 100}
+	for x, w := range want {
+		if got := pgm.data[0][x]; got != w {
+			t.Errorf("sample %d: got %d, want %d", samples[x], got, w)
+		}
+	}
+	if pgm.max != 100 {
+		t.Fatalf("max: got %d, want 100", pgm.max)
+	}
+}
+
+func TestPGMRescaleTo16Bit(t *testing.T) {
+	pgm := newTestPGM(3, 1, func(x, y int) uint16 { return []uint16{1, 128, 255}[x] })
+	if err := pgm.Rescale(65535); err != nil {
+		t.Fatal(err)
+	}
+	for x, w := range []uint16{257, 32896, 65535} {
+		if got := pgm.data[0][x]; got != w {
+			t.Errorf("x=%d: got %d, want %d", x, got, w)
+		}
+	}
+	if err := pgm.Rescale(255); err != nil {
+		t.Fatal(err)
+	}
+	for x, w := range []uint16{1, 128, 255} {
+		if got := pgm.data[0][x]; got != w {
+			t.Errorf("round trip x=%d: got %d, want %d", x, got, w)
+		}
+	}
+}
+
+func TestPGMRescaleFromZeroMax(t *testing.T) {
+	pgm := newTestPGM(2, 1, func(x, y int) uint16 { return 7 })
+	pgm.max = 0
+	if err := pgm.Rescale(255); err != nil {
+		t.Fatal(err)
+	}
+	if pgm.data[0][0] != 0 || pgm.data[0][1] != 0 || pgm.max != 255 {
+		t.Fatalf("got %v max %d, want zero samples and max 255", pgm.data[0], pgm.max)
+	}
+}
+
+func TestPGMRescaleInvalidMax(t *testing.T) {
+	pgm := newTestPGM(1, 1, func(x, y int) uint16 { return 200 })
+	for _, newMax := range []int{0, -1, 65536} {
+		if err := pgm.Rescale(newMax); err == nil {
+			t.Errorf("Rescale(%d) accepted", newMax)
+		}
+		if err := pgm.Clamp(newMax); err == nil {
+			t.Errorf("Clamp(%d) accepted", newMax)
+		}
+	}
+	pgm.SetMaxValue(0)
+	if pgm.max != 255 || pgm.data[0][0] != 200 {
+		t.Fatalf("SetMaxValue(0) changed the image: max %d, sample %d", pgm.max, pgm.data[0][0])
+	}
+}
+
+func TestPGMClampVersusRescale(t *testing.T) {
+	fill := func(x, y int) uint16 { return []uint16{50, 200}[x] }
+	clamped := newTestPGM(2, 1, fill)
+	if err := clamped.Clamp(100); err != nil {
+		t.Fatal(err)
+	}
+	if clamped.data[0][0] != 50 || clamped.data[0][1] != 100 {
+		t.Fatalf("Clamp: got %v, want [50 100]", clamped.data[0])
+	}
+	rescaled := newTestPGM(2, 1, fill)
+	rescaled.SetMaxValue(100)
+	if rescaled.data[0][0] != 20 || rescaled.data[0][1] != 78 || rescaled.max != 100 {
+		t.Fatalf("SetMaxValue: got %v max %d, want [20 78] max 100", rescaled.data[0], rescaled.max)
+	}
+}
+
+func TestPGMSaveP5SixteenBit(t *testing.T) {
+	pgm := newTestPGM(2, 1, func(x, y int) uint16 { return []uint16{0x0102, 0xfffe}[x] })
+	pgm.max = 65535
+	pgm.SetMagicNumber("P5")
+	filename := filepath.Join(t.TempDir(), "image.pgm")
+	if err := pgm.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte("P5\n2 1\n65535\n\x01\x02\xff\xfe")
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
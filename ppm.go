@@ -1,6 +1,7 @@
 package Netpbm
 
 import (
+	"bufio"
 	"fmt"
 	"math"
 	"os"
@@ -12,11 +13,11 @@ type PPM struct {
 	data          [][]Pixel
 	width, height int
 	magicNumber   string
-	max           uint8
+	max           int
 }
 
 type Pixel struct {
-	R, G, B uint8
+	R, G, B uint16
 }
 
 func ReadPPM(filename string) (*PPM, error) {
@@ -83,11 +84,11 @@ func ReadPPM(filename string) (*PPM, error) {
 				if b > maxval {
 					b = maxval
 				}
-				data[i][j/3] = Pixel{R: uint8(r), G: uint8(g), B: uint8(b)}
+				data[i][j/3] = Pixel{R: uint16(r), G: uint16(g), B: uint16(b)}
 			}
 		}
 	}
-	return &PPM{data: data, width: width, height: height, magicNumber: magicNumber, max: uint8(maxval)}, err
+	return &PPM{data: data, width: width, height: height, magicNumber: magicNumber, max: maxval}, err
 }
 
 func display(data [][]Pixel) {
@@ -116,11 +117,12 @@ func (ppm *PPM) Set(x, y int, value Pixel) {
 
 // Invert inverts the colors of the PPM image.
 func (ppm *PPM) Invert() {
+	maxVal := uint16(ppm.max)
 	for i := 0; i < len(ppm.data); i++ {
 		for j := 0; j < len(ppm.data[0]); j++ {
-			ppm.data[i][j].R = ppm.max - ppm.data[i][j].R
-			ppm.data[i][j].G = ppm.max - ppm.data[i][j].G
-			ppm.data[i][j].B = ppm.max - ppm.data[i][j].B
+			ppm.data[i][j].R = maxVal - ppm.data[i][j].R
+			ppm.data[i][j].G = maxVal - ppm.data[i][j].G
+			ppm.data[i][j].B = maxVal - ppm.data[i][j].B
 		}
 	}
 }
@@ -140,11 +142,12 @@ func (ppm *PPM) Flip() {
 
 // Flop flops the PPM image vertically.
 func (ppm *PPM) Flop() {
-	// Height = Colums
-	// Width = Rows
-	NumRows := ppm.width
+	NumRows := ppm.height
 	for i := 0; i < NumRows/2; i++ {
-		ppm.data[i], ppm.data[NumRows-i-1] = ppm.data[NumRows-i-1], ppm.data[i]
+		// Swap pixels rather than rows so that the operation works on a view
+		for j := range ppm.data[i] {
+			ppm.data[i][j], ppm.data[NumRows-i-1][j] = ppm.data[NumRows-i-1][j], ppm.data[i][j]
+		}
 	}
 }
 
@@ -152,39 +155,146 @@ func (ppm *PPM) SetMagicNumber(magicNumber string) {
 	ppm.magicNumber = magicNumber
 }
 
-// SetMaxValue sets the max value of the PPM image.
-func (ppm *PPM) SetMaxValue(maxValue uint8) {
-	oldMax := ppm.max
-	ppm.max = maxValue
-	for i := 0; i < len(ppm.data); i++ {
-		for j := 0; j < len(ppm.data[0]); j++ {
-			ppm.data[i][j].R = uint8(float64(ppm.data[i][j].R) * float64(ppm.max) / float64(oldMax))
-			ppm.data[i][j].G = uint8(float64(ppm.data[i][j].G) * float64(ppm.max) / float64(oldMax))
-			ppm.data[i][j].B = uint8(float64(ppm.data[i][j].B) * float64(ppm.max) / float64(oldMax))
+// Save writes the PPM image to a file, as P3 (ASCII) or P6 (binary)
+// depending on the magic number.
+func (ppm *PPM) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	// Write the header
+	_, err = fmt.Fprintf(writer, "%s\n%d %d\n%d\n", ppm.magicNumber, ppm.width, ppm.height, ppm.max)
+	if err != nil {
+		return err
+	}
+
+	// Write the pixel data
+	if ppm.magicNumber == "P3" {
+		for _, row := range ppm.data {
+			for j, pixel := range row {
+				if j > 0 {
+					_, err = writer.WriteString(" ")
+					if err != nil {
+						return err
+					}
+				}
+				_, err = fmt.Fprintf(writer, "%d %d %d", pixel.R, pixel.G, pixel.B)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = writer.WriteString("\n")
+			if err != nil {
+				return err
+			}
+		}
+	} else if ppm.magicNumber == "P6" {
+		for _, row := range ppm.data {
+			for _, pixel := range row {
+				for _, sample := range []uint16{pixel.R, pixel.G, pixel.B} {
+					err = writeSample(writer, sample, ppm.max)
+					if err != nil {
+						return err
+					}
+				}
+			}
 		}
+	} else {
+		return fmt.Errorf("unsupported PPM format: %s", ppm.magicNumber)
 	}
+
+	return writer.Flush()
 }
 
-// Rotate90CW rotates the PPM image 90Â° clockwise.
-func (ppm *PPM) Rotate90CW() {
-	// Height = Colums = Colonne vers le bas
-	// Width = Rows = Ligne vers la droite
-	NumRows := ppm.width
-	NumColums := ppm.height
-	for i := 0; i < NumColums; i++ {
-		for j := i + 1; j < NumRows; j++ {
-			vartemp := ppm.data[i][j]
-			ppm.data[i][j] = ppm.data[j][i]
-			ppm.data[j][i] = vartemp
+// SetMaxValue sets the max value of the PPM image, rescaling the samples.
+// It is a thin wrapper around Rescale that ignores invalid max values such
+// as 0; call Rescale directly to get the error.
+func (ppm *PPM) SetMaxValue(maxValue uint8) {
+	_ = ppm.Rescale(int(maxValue))
+}
+
+// Rescale changes the max value of the PPM image and maps every sample
+// proportionally to the new range, rounding to the nearest value.
+func (ppm *PPM) Rescale(newMax int) error {
+	if err := checkMaxValue(newMax); err != nil {
+		return err
+	}
+	for i := range ppm.data {
+		for j := range ppm.data[i] {
+			p := &ppm.data[i][j]
+			p.R = rescaleSample(p.R, ppm.max, newMax)
+			p.G = rescaleSample(p.G, ppm.max, newMax)
+			p.B = rescaleSample(p.B, ppm.max, newMax)
 		}
 	}
-	for i := 0; i < NumColums; i++ {
-		for j := 0; j < NumRows/2; j++ {
-			vartemp := ppm.data[i][j]
-			ppm.data[i][j] = ppm.data[i][NumRows-j-1]
-			ppm.data[i][NumRows-j-1] = vartemp
+	ppm.max = newMax
+	return nil
+}
+
+// Clamp changes the max value of the PPM image and clips the samples that
+// exceed it, leaving the others untouched.
+func (ppm *PPM) Clamp(newMax int) error {
+	if err := checkMaxValue(newMax); err != nil {
+		return err
+	}
+	maxVal := uint16(newMax)
+	for i := range ppm.data {
+		for j := range ppm.data[i] {
+			p := &ppm.data[i][j]
+			if p.R > maxVal {
+				p.R = maxVal
+			}
+			if p.G > maxVal {
+				p.G = maxVal
+			}
+			if p.B > maxVal {
+				p.B = maxVal
+			}
 		}
 	}
+	ppm.max = newMax
+	return nil
+}
+
+// Rotate90CW rotates the PPM image 90° clockwise.
+func (ppm *PPM) Rotate90CW() {
+	ppm.data = storeGrid(ppm.data, rotate90CW(ppm.data, ppm.width, ppm.height))
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Rotate90CCW rotates the PPM image 90° counterclockwise.
+func (ppm *PPM) Rotate90CCW() {
+	ppm.data = storeGrid(ppm.data, rotate90CCW(ppm.data, ppm.width, ppm.height))
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Rotate180 rotates the PPM image by 180°.
+func (ppm *PPM) Rotate180() {
+	ppm.data = storeGrid(ppm.data, rotate180(ppm.data, ppm.width, ppm.height))
+}
+
+// Rotate270CW rotates the PPM image 270° clockwise, which is the same as
+// Rotate90CCW.
+func (ppm *PPM) Rotate270CW() {
+	ppm.Rotate90CCW()
+}
+
+// Transpose mirrors the PPM image across its main diagonal (top-left to
+// bottom-right).
+func (ppm *PPM) Transpose() {
+	ppm.data = storeGrid(ppm.data, transpose(ppm.data, ppm.width, ppm.height))
+	ppm.width, ppm.height = ppm.height, ppm.width
+}
+
+// Transverse mirrors the PPM image across its anti-diagonal (top-right to
+// bottom-left).
+func (ppm *PPM) Transverse() {
+	ppm.data = storeGrid(ppm.data, transverse(ppm.data, ppm.width, ppm.height))
+	ppm.width, ppm.height = ppm.height, ppm.width
 }
 
 // ToPGM converts the PPM image to PGM.
@@ -200,18 +310,34 @@ func (ppm *PPM) ToPGM() *PGM {
 	}
 	Numrows := ppm.width
 	NumColumns := ppm.height
-	var newdata = make([][]uint8, NumColumns)
+	var newdata = make([][]uint16, NumColumns)
 	for i := 0; i < NumColumns; i++ {
-		newdata[i] = make([]uint8, Numrows)
+		newdata[i] = make([]uint16, Numrows)
 		for j := 0; j < Numrows; j++ {
 			{
-				newdata[i][j] = uint8((int(ppm.data[i][j].R) + int(ppm.data[i][j].G) + int(ppm.data[i][j].B)) / 3)
+				newdata[i][j] = uint16((int(ppm.data[i][j].R) + int(ppm.data[i][j].G) + int(ppm.data[i][j].B)) / 3)
 			}
 		}
 	}
 	return &PGM{data: newdata, width: Numrows, height: NumColumns, max: ppm.max, magicNumber: newmagicnumber}
 }
 
+// grayMagicNumber returns the PGM magic number matching a PPM one.
+func grayMagicNumber(magicNumber string) string {
+	if magicNumber == "P6" {
+		return "P5"
+	}
+	return "P2"
+}
+
+// colorMagicNumber returns the PPM magic number matching a PGM one.
+func colorMagicNumber(magicNumber string) string {
+	if magicNumber == "P5" {
+		return "P6"
+	}
+	return "P3"
+}
+
 // ToPBM converts the PPM image to PBM.
 func (ppm *PPM) ToPBM() *PBM {
 	var newmagicnumber string
@@ -227,7 +353,7 @@ func (ppm *PPM) ToPBM() *PBM {
 	for i := 0; i < NumColumns; i++ {
 		newdata[i] = make([]bool, Numrows)
 		for j := 0; j < Numrows; j++ {
-			newdata[i][j] = uint8((int(ppm.data[i][j].R)+int(ppm.data[i][j].G)+int(ppm.data[i][j].B))/3) < ppm.max/2
+			newdata[i][j] = uint16((int(ppm.data[i][j].R)+int(ppm.data[i][j].G)+int(ppm.data[i][j].B))/3) < uint16(ppm.max/2)
 		}
 	}
 	return &PBM{data: newdata, width: Numrows, height: NumColumns, magicNumber: newmagicnumber}
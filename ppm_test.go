@@ -0,0 +1,91 @@
+package Netpbm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestPPM returns a P3 image with maxval 255 whose pixels are given by fill.
+func newTestPPM(width, height int, fill func(x, y int) Pixel) *PPM {
+	data := make([][]Pixel, height)
+	for y := range data {
+		data[y] = make([]Pixel, width)
+		for x := range data[y] {
+			data[y][x] = fill(x, y)
+		}
+	}
+	return &PPM{data: data, width: width, height: height, magicNumber: "P3", max: 255}
+}
+
+func TestPPMSaveP3(t *testing.T) {
+	ppm := newTestPPM(3, 2, func(x, y int) Pixel { return Pixel{uint16(10 * x), uint16(20 * y), 200} })
+	filename := filepath.Join(t.TempDir(), "image.ppm")
+	if err := ppm.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPPM(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.width != 3 || read.height != 2 || read.max != 255 {
+		t.Fatalf("got %dx%d max %d, want 3x2 max 255", read.width, read.height, read.max)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			if read.data[y][x] != ppm.data[y][x] {
+				t.Fatalf("pixel (%d, %d): got %v, want %v", x, y, read.data[y][x], ppm.data[y][x])
+			}
+		}
+	}
+}
+
+func TestPPMSaveUnknownFormat(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{} })
+	ppm.SetMagicNumber("P9")
+	if err := ppm.Save(filepath.Join(t.TempDir(), "image.ppm")); err == nil {
+		t.Fatal("expected an error for an unknown magic number")
+	}
+}
+
+func TestPPMRescaleAndClamp(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{1, 128, 255} })
+	if err := ppm.Rescale(65535); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{257, 32896, 65535}) {
+		t.Fatalf("Rescale: got %v", got)
+	}
+	if err := ppm.Clamp(1000); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{257, 1000, 1000}) || ppm.max != 1000 {
+		t.Fatalf("Clamp: got %v max %d", got, ppm.max)
+	}
+	if err := ppm.Rescale(0); err == nil {
+		t.Fatal("Rescale(0) accepted")
+	}
+	ppm.SetMaxValue(0)
+	if ppm.max != 1000 {
+		t.Fatalf("SetMaxValue(0) changed the max to %d", ppm.max)
+	}
+}
+
+func TestPPMSaveP6SixteenBit(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{0x0102, 0x0304, 0xfffe} })
+	ppm.max = 65535
+	ppm.SetMagicNumber("P6")
+	filename := filepath.Join(t.TempDir(), "image.ppm")
+	if err := ppm.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte("P6\n1 1\n65535\n\x01\x02\x03\x04\xff\xfe")
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,268 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleFilter selects the kernel used to compute the pixels of a resized
+// image.
+type ResampleFilter int
+
+const (
+	// FilterNearest copies the closest source pixel.
+	FilterNearest ResampleFilter = iota
+	// FilterBox averages the source pixels covered by each output pixel.
+	FilterBox
+	// FilterBilinear uses a triangle (tent) kernel.
+	FilterBilinear
+	// FilterCatmullRom is a sharp bicubic kernel (B = 0, C = 0.5).
+	FilterCatmullRom
+	// FilterMitchell is a balanced bicubic kernel (B = C = 1/3).
+	FilterMitchell
+	// FilterLanczos3 is a windowed sinc kernel with three lobes.
+	FilterLanczos3
+)
+
+// support returns the radius of the kernel, in source pixels at scale 1.
+func (f ResampleFilter) support() float64 {
+	switch f {
+	case FilterBox:
+		return 0.5
+	case FilterBilinear:
+		return 1
+	case FilterCatmullRom, FilterMitchell:
+		return 2
+	case FilterLanczos3:
+		return 3
+	}
+	return 0
+}
+
+// kernel evaluates the filter at distance t.
+func (f ResampleFilter) kernel(t float64) float64 {
+	switch f {
+	case FilterBox:
+		if t >= -0.5 && t < 0.5 {
+			return 1
+		}
+		return 0
+	case FilterBilinear:
+		return math.Max(0, 1-math.Abs(t))
+	case FilterCatmullRom:
+		return catmullRom(t)
+	case FilterMitchell:
+		return mitchell(t)
+	case FilterLanczos3:
+		if t <= -3 || t >= 3 {
+			return 0
+		}
+		return sinc(t) * sinc(t/3)
+	}
+	return 0
+}
+
+func mitchell(t float64) float64 {
+	const b, c = 1.0 / 3, 1.0 / 3
+	t = math.Abs(t)
+	switch {
+	case t < 1:
+		return ((12-9*b-6*c)*t*t*t + (-18+12*b+6*c)*t*t + (6 - 2*b)) / 6
+	case t < 2:
+		return ((-b-6*c)*t*t*t + (6*b+30*c)*t*t + (-12*b-48*c)*t + (8*b + 24*c)) / 6
+	}
+	return 0
+}
+
+func sinc(t float64) float64 {
+	if t == 0 {
+		return 1
+	}
+	t *= math.Pi
+	return math.Sin(t) / t
+}
+
+// resampleTap is the weight given to one source pixel.
+type resampleTap struct {
+	index  int
+	weight float64
+}
+
+// resampleWeights precomputes, for each of the dstSize output pixels, the
+// normalized weights of the source pixels along one axis. When shrinking the
+// kernel is stretched so that every source pixel contributes.
+func resampleWeights(srcSize, dstSize int, filter ResampleFilter) [][]resampleTap {
+	scale := float64(srcSize) / float64(dstSize)
+	filterScale := math.Max(scale, 1)
+	support := filter.support() * filterScale
+
+	taps := make([][]resampleTap, dstSize)
+	for i := range taps {
+		center := (float64(i) + 0.5) * scale
+		if filter == FilterNearest {
+			taps[i] = []resampleTap{{index: clamp(int(center), 0, srcSize-1), weight: 1}}
+			continue
+		}
+		start := int(math.Floor(center - support))
+		end := int(math.Ceil(center + support))
+		sum := 0.0
+		for j := start; j <= end; j++ {
+			w := filter.kernel((float64(j) + 0.5 - center) / filterScale)
+			if w == 0 {
+				continue
+			}
+			// Pixels outside the image repeat the edge
+			taps[i] = append(taps[i], resampleTap{index: clamp(j, 0, srcSize-1), weight: w})
+			sum += w
+		}
+		if sum == 0 {
+			taps[i] = []resampleTap{{index: clamp(int(center), 0, srcSize-1), weight: 1}}
+			continue
+		}
+		for k := range taps[i] {
+			taps[i][k].weight /= sum
+		}
+	}
+	return taps
+}
+
+// resamplePlane scales a plane to newWidth x newHeight with a separable
+// filter, rounding and clamping the results to [0, max].
+func resamplePlane(data [][]uint16, width, height, newWidth, newHeight int, filter ResampleFilter, max int) [][]uint16 {
+	return fromFloatPlane(resampleFloat(data, width, height, newWidth, newHeight, filter), newWidth, newHeight, max)
+}
+
+// resampleFloat scales a plane like resamplePlane but keeps the unrounded
+// results.
+func resampleFloat(data [][]uint16, width, height, newWidth, newHeight int, filter ResampleFilter) [][]float64 {
+	xTaps := resampleWeights(width, newWidth, filter)
+	yTaps := resampleWeights(height, newHeight, filter)
+
+	// Horizontal pass
+	tmp := newGrid[float64](newWidth, height)
+	for y := 0; y < height; y++ {
+		for x, taps := range xTaps {
+			sum := 0.0
+			for _, t := range taps {
+				sum += float64(data[y][t.index]) * t.weight
+			}
+			tmp[y][x] = sum
+		}
+	}
+
+	// Vertical pass
+	out := newGrid[float64](newWidth, newHeight)
+	for y, taps := range yTaps {
+		for x := 0; x < newWidth; x++ {
+			sum := 0.0
+			for _, t := range taps {
+				sum += tmp[t.index][x] * t.weight
+			}
+			out[y][x] = sum
+		}
+	}
+	return out
+}
+
+func checkResize(width, height, newWidth, newHeight int) error {
+	if newWidth <= 0 || newHeight <= 0 {
+		return fmt.Errorf("invalid size: %dx%d", newWidth, newHeight)
+	}
+	if width == 0 || height == 0 {
+		return fmt.Errorf("cannot resize an empty image")
+	}
+	return nil
+}
+
+// Resize scales the PGM image to newWidth x newHeight using the given filter.
+func (pgm *PGM) Resize(newWidth, newHeight int, filter ResampleFilter) error {
+	if err := checkResize(pgm.width, pgm.height, newWidth, newHeight); err != nil {
+		return err
+	}
+	pgm.data = storeGrid(pgm.data, resamplePlane(pgm.data, pgm.width, pgm.height, newWidth, newHeight, filter, pgm.max))
+	pgm.width, pgm.height = newWidth, newHeight
+	return nil
+}
+
+// Resize scales the PPM image to newWidth x newHeight using the given filter.
+func (ppm *PPM) Resize(newWidth, newHeight int, filter ResampleFilter) error {
+	if err := checkResize(ppm.width, ppm.height, newWidth, newHeight); err != nil {
+		return err
+	}
+	width, height := ppm.width, ppm.height
+	ppm.mapChannels(newWidth, newHeight, func(c Channel, plane [][]uint16) [][]uint16 {
+		return resamplePlane(plane, width, height, newWidth, newHeight, filter, ppm.max)
+	})
+	return nil
+}
+
+// Resize scales the PBM image to newWidth x newHeight. Each output pixel is
+// black when at least half of the area it covers is black.
+func (pbm *PBM) Resize(newWidth, newHeight int) error {
+	if err := checkResize(pbm.width, pbm.height, newWidth, newHeight); err != nil {
+		return err
+	}
+	plane := newGrid[uint16](pbm.width, pbm.height)
+	for y := range plane {
+		for x := range plane[y] {
+			if pbm.data[y][x] {
+				plane[y][x] = 255
+			}
+		}
+	}
+	plane = resamplePlane(plane, pbm.width, pbm.height, newWidth, newHeight, FilterBox, 255)
+	out := newGrid[bool](newWidth, newHeight)
+	for y := range plane {
+		for x := range plane[y] {
+			out[y][x] = plane[y][x] >= 128
+		}
+	}
+	pbm.data = storeGrid(pbm.data, out)
+	pbm.width, pbm.height = newWidth, newHeight
+	return nil
+}
+
+// scaleGrid enlarges a grid by an integer factor, each pixel becoming a
+// factor x factor block.
+func scaleGrid[T any](data [][]T, width, height, factor int) [][]T {
+	out := newGrid[T](width*factor, height*factor)
+	for y := range out {
+		for x := range out[y] {
+			out[y][x] = data[y/factor][x/factor]
+		}
+	}
+	return out
+}
+
+// ScaleInteger enlarges the PBM image by an integer factor without any
+// smoothing, for pixel art.
+func (pbm *PBM) ScaleInteger(factor int) error {
+	if factor < 1 {
+		return fmt.Errorf("invalid scale factor: %d", factor)
+	}
+	pbm.data = storeGrid(pbm.data, scaleGrid(pbm.data, pbm.width, pbm.height, factor))
+	pbm.width, pbm.height = pbm.width*factor, pbm.height*factor
+	return nil
+}
+
+// ScaleInteger enlarges the PGM image by an integer factor without any
+// smoothing, for pixel art.
+func (pgm *PGM) ScaleInteger(factor int) error {
+	if factor < 1 {
+		return fmt.Errorf("invalid scale factor: %d", factor)
+	}
+	pgm.data = storeGrid(pgm.data, scaleGrid(pgm.data, pgm.width, pgm.height, factor))
+	pgm.width, pgm.height = pgm.width*factor, pgm.height*factor
+	return nil
+}
+
+// ScaleInteger enlarges the PPM image by an integer factor without any
+// smoothing, for pixel art.
+func (ppm *PPM) ScaleInteger(factor int) error {
+	if factor < 1 {
+		return fmt.Errorf("invalid scale factor: %d", factor)
+	}
+	ppm.data = storeGrid(ppm.data, scaleGrid(ppm.data, ppm.width, ppm.height, factor))
+	ppm.width, ppm.height = ppm.width*factor, ppm.height*factor
+	return nil
+}
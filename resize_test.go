@@ -0,0 +1,117 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var allFilters = []ResampleFilter{FilterNearest, FilterBox, FilterBilinear, FilterCatmullRom, FilterMitchell, FilterLanczos3}
+
+func TestResizeKeepsFlatImages(t *testing.T) {
+	for _, filter := range allFilters {
+		for _, size := range [][2]int{{3, 2}, {13, 9}} {
+			pgm := newTestPGM(6, 5, func(x, y int) uint16 { return 77 })
+			if err := pgm.Resize(size[0], size[1], filter); err != nil {
+				t.Fatal(err)
+			}
+			for y := range pgm.data {
+				for x, v := range pgm.data[y] {
+					if v != 77 {
+						t.Fatalf("filter %d, %dx%d: (%d, %d) = %d", filter, size[0], size[1], x, y, v)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestResizeBoxAverages(t *testing.T) {
+	pgm := newTestPGM(4, 2, func(x, y int) uint16 { return []uint16{0, 100, 200, 40}[x] + uint16(20*y) })
+	if err := pgm.Resize(2, 1, FilterBox); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]uint16{{60, 130}}; !reflect.DeepEqual(pgm.data, want) {
+		t.Fatalf("got %v, want %v", pgm.data, want)
+	}
+}
+
+func TestResizeNearestReplicates(t *testing.T) {
+	pgm := newTestPGM(2, 1, func(x, y int) uint16 { return uint16(10 + x) })
+	if err := pgm.Resize(4, 2, FilterNearest); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]uint16{{10, 10, 11, 11}, {10, 10, 11, 11}}; !reflect.DeepEqual(pgm.data, want) {
+		t.Fatalf("got %v, want %v", pgm.data, want)
+	}
+}
+
+func TestResizeBilinearRampIsMonotone(t *testing.T) {
+	pgm := newTestPGM(4, 1, func(x, y int) uint16 { return uint16(60 * x) })
+	if err := pgm.Resize(10, 1, FilterBilinear); err != nil {
+		t.Fatal(err)
+	}
+	row := pgm.data[0]
+	for x := 1; x < len(row); x++ {
+		if row[x] < row[x-1] {
+			t.Fatalf("not monotone: %v", row)
+		}
+	}
+	if row[0] != 0 || row[9] != 180 {
+		t.Fatalf("ends %d, %d; want 0, 180", row[0], row[9])
+	}
+}
+
+func TestResampleKernels(t *testing.T) {
+	for _, filter := range []ResampleFilter{FilterBilinear, FilterCatmullRom, FilterLanczos3} {
+		if k := filter.kernel(0); math.Abs(k-1) > 1e-12 {
+			t.Errorf("filter %d: kernel(0) = %g", filter, k)
+		}
+		for _, n := range []float64{1, 2, -1} {
+			if k := filter.kernel(n); math.Abs(k) > 1e-12 {
+				t.Errorf("filter %d: kernel(%g) = %g", filter, n, k)
+			}
+		}
+	}
+	// Mitchell is not interpolating: it blurs slightly.
+	if k := FilterMitchell.kernel(1); math.Abs(k-1.0/18) > 1e-12 {
+		t.Errorf("Mitchell kernel(1) = %g, want 1/18", k)
+	}
+}
+
+func TestPPMResize(t *testing.T) {
+	ppm := newTestPPM(2, 2, func(x, y int) Pixel { return Pixel{uint16(200 * x), 50, uint16(200 * y)} })
+	if err := ppm.Resize(1, 1, FilterBox); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{100, 50, 100}) {
+		t.Fatalf("got %v, want {100 50 100}", got)
+	}
+}
+
+func TestPBMResizeAndScaleInteger(t *testing.T) {
+	pbm := newTestPBM(
+		"##..",
+		"#...",
+		"....",
+		"...#",
+	)
+	if err := pbm.Resize(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"#.", ".."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("Resize: got %v, want %v", pbmRows(pbm), want)
+	}
+	if err := pbm.ScaleInteger(2); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"##..", "##..", "....", "...."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("ScaleInteger: got %v, want %v", pbmRows(pbm), want)
+	}
+	if err := pbm.ScaleInteger(0); err == nil {
+		t.Error("factor 0 accepted")
+	}
+	if err := pbm.Resize(0, 2); err == nil {
+		t.Error("empty size accepted")
+	}
+}
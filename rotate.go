@@ -0,0 +1,67 @@
+package Netpbm
+
+import "math"
+
+// RotateOptions controls an arbitrary-angle rotation.
+type RotateOptions struct {
+	// Interpolation used to read the source pixels.
+	Interpolation Interpolation
+	// Expand grows the canvas so the whole rotated image fits. Otherwise the
+	// image keeps its size and the corners are cut off.
+	Expand bool
+}
+
+// rotationMapping returns the size of the rotated canvas and the mapping from
+// its pixels back to the source image. Positive angles turn the image
+// counterclockwise.
+func rotationMapping(width, height int, degrees float64, expand bool) (int, int, pointMapping) {
+	theta := degrees * math.Pi / 180
+	cos, sin := math.Cos(theta), math.Sin(theta)
+
+	outWidth, outHeight := width, height
+	if expand {
+		// The small epsilon avoids an extra column at 90° due to rounding errors
+		w := math.Abs(float64(width)*cos) + math.Abs(float64(height)*sin)
+		h := math.Abs(float64(width)*sin) + math.Abs(float64(height)*cos)
+		outWidth = int(math.Ceil(w - 1e-9))
+		outHeight = int(math.Ceil(h - 1e-9))
+	}
+
+	cx, cy := float64(width)/2, float64(height)/2
+	ocx, ocy := float64(outWidth)/2, float64(outHeight)/2
+	mapping := func(x, y float64) (float64, float64, bool) {
+		dx, dy := x+0.5-ocx, y+0.5-ocy
+		sx := dx*cos - dy*sin + cx - 0.5
+		sy := dx*sin + dy*cos + cy - 0.5
+		return sx, sy, true
+	}
+	return outWidth, outHeight, mapping
+}
+
+// Rotate rotates the PBM image by an arbitrary angle in degrees,
+// counterclockwise. Uncovered areas are set to background. Bitmaps are always
+// sampled with nearest neighbour, so opts.Interpolation is ignored.
+func (pbm *PBM) Rotate(degrees float64, opts RotateOptions, background bool) {
+	w, h, mapping := rotationMapping(pbm.width, pbm.height, degrees, opts.Expand)
+	pbm.data = storeGrid(pbm.data, remapNearest(pbm.data, pbm.width, pbm.height, w, h, mapping, background))
+	pbm.width, pbm.height = w, h
+}
+
+// Rotate rotates the PGM image by an arbitrary angle in degrees,
+// counterclockwise. Uncovered areas are set to background.
+func (pgm *PGM) Rotate(degrees float64, opts RotateOptions, background uint16) {
+	w, h, mapping := rotationMapping(pgm.width, pgm.height, degrees, opts.Expand)
+	if opts.Interpolation == NearestNeighbor {
+		pgm.data = storeGrid(pgm.data, remapNearest(pgm.data, pgm.width, pgm.height, w, h, mapping, background))
+	} else {
+		pgm.data = storeGrid(pgm.data, remapPlane(pgm.data, pgm.width, pgm.height, w, h, mapping, opts.Interpolation, background, pgm.max))
+	}
+	pgm.width, pgm.height = w, h
+}
+
+// Rotate rotates the PPM image by an arbitrary angle in degrees,
+// counterclockwise. Uncovered areas are set to background.
+func (ppm *PPM) Rotate(degrees float64, opts RotateOptions, background Pixel) {
+	w, h, mapping := rotationMapping(ppm.width, ppm.height, degrees, opts.Expand)
+	remapPPM(ppm, w, h, mapping, opts.Interpolation, background)
+}
@@ -0,0 +1,79 @@
+package Netpbm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRotateRightAngleMatchesRotate90CCW(t *testing.T) {
+	for _, interp := range []Interpolation{NearestNeighbor, Bilinear, Bicubic} {
+		pgm := newTransformPGM()
+		pgm.Rotate(90, RotateOptions{Interpolation: interp, Expand: true}, 0)
+		want := newTransformPGM()
+		want.Rotate90CCW()
+		if pgm.width != want.width || pgm.height != want.height {
+			t.Fatalf("interpolation %d: size %dx%d, want %dx%d", interp, pgm.width, pgm.height, want.width, want.height)
+		}
+		if !reflect.DeepEqual(pgm.data, want.data) {
+			t.Errorf("interpolation %d: got %v, want %v", interp, pgm.data, want.data)
+		}
+	}
+}
+
+func TestRotateZeroIsIdentity(t *testing.T) {
+	pgm := newTestPGM(5, 4, func(x, y int) uint16 { return uint16(x*40 + y*7) })
+	pgm.Rotate(0, RotateOptions{Interpolation: Bicubic}, 255)
+	want := newTestPGM(5, 4, func(x, y int) uint16 { return uint16(x*40 + y*7) })
+	if !reflect.DeepEqual(pgm.data, want.data) {
+		t.Fatalf("got %v", pgm.data)
+	}
+}
+
+func TestRotateBackgroundAndExpand(t *testing.T) {
+	pgm := newTestPGM(10, 10, func(x, y int) uint16 { return 100 })
+	pgm.Rotate(45, RotateOptions{Interpolation: Bilinear}, 7)
+	if pgm.width != 10 || pgm.height != 10 {
+		t.Fatalf("size changed to %dx%d without Expand", pgm.width, pgm.height)
+	}
+	if pgm.data[0][0] != 7 || pgm.data[5][5] != 100 {
+		t.Fatalf("corner %d, center %d", pgm.data[0][0], pgm.data[5][5])
+	}
+
+	expanded := newTestPGM(10, 10, func(x, y int) uint16 { return 100 })
+	expanded.Rotate(45, RotateOptions{Expand: true}, 7)
+	if expanded.width != 15 || expanded.height != 15 {
+		t.Fatalf("expanded size %dx%d, want 15x15", expanded.width, expanded.height)
+	}
+}
+
+func TestPPMRotateBackground(t *testing.T) {
+	ppm := newTestPPM(10, 10, func(x, y int) Pixel { return Pixel{255, 255, 255} })
+	background := Pixel{1, 2, 3}
+	ppm.Rotate(45, RotateOptions{Interpolation: Bilinear}, background)
+	if got := ppm.data[0][0]; got != background {
+		t.Fatalf("corner: got %v, want %v", got, background)
+	}
+}
+
+func TestPBMRotateOptions(t *testing.T) {
+	pbm := newTestPBM(
+		"##.",
+		"...",
+	)
+	pbm.Rotate(-90, RotateOptions{Expand: true}, false)
+	want := []string{".#", ".#", ".."}
+	if got := pbmRows(pbm); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	rows := make([]string, 10)
+	for i := range rows {
+		rows[i] = strings.Repeat(".", 10)
+	}
+	square := newTestPBM(rows...)
+	square.Rotate(45, RotateOptions{Interpolation: Bicubic}, true)
+	if !square.data[0][0] || square.data[5][5] {
+		t.Fatalf("got %v", pbmRows(square))
+	}
+}
@@ -0,0 +1,127 @@
+package Netpbm
+
+// Rectangle is an area of an image going from Min (included) to Max
+// (excluded).
+type Rectangle struct {
+	Min, Max Point
+}
+
+// Rect returns the rectangle with corners (x0, y0) and (x1, y1), swapping
+// coordinates if needed so that Min is the top-left corner.
+func Rect(x0, y0, x1, y1 int) Rectangle {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	return Rectangle{Point{x0, y0}, Point{x1, y1}}
+}
+
+// Dx returns the width of the rectangle.
+func (r Rectangle) Dx() int {
+	return r.Max.X - r.Min.X
+}
+
+// Dy returns the height of the rectangle.
+func (r Rectangle) Dy() int {
+	return r.Max.Y - r.Min.Y
+}
+
+// Empty reports whether the rectangle contains no pixel.
+func (r Rectangle) Empty() bool {
+	return r.Min.X >= r.Max.X || r.Min.Y >= r.Max.Y
+}
+
+// Intersect returns the largest rectangle contained in both r and s.
+func (r Rectangle) Intersect(s Rectangle) Rectangle {
+	if r.Min.X < s.Min.X {
+		r.Min.X = s.Min.X
+	}
+	if r.Min.Y < s.Min.Y {
+		r.Min.Y = s.Min.Y
+	}
+	if r.Max.X > s.Max.X {
+		r.Max.X = s.Max.X
+	}
+	if r.Max.Y > s.Max.Y {
+		r.Max.Y = s.Max.Y
+	}
+	if r.Empty() {
+		return Rectangle{}
+	}
+	return r
+}
+
+// subGrid returns rows sharing the storage of the given grid for the area r,
+// which must lie inside the grid. The capacity of each row is limited so that
+// an append can never write over the parent's pixels.
+func subGrid[T any](data [][]T, r Rectangle) [][]T {
+	rows := make([][]T, r.Dy())
+	for i := range rows {
+		rows[i] = data[r.Min.Y+i][r.Min.X:r.Max.X:r.Max.X]
+	}
+	return rows
+}
+
+// storeGrid returns the grid to keep as the pixels of an image after an
+// operation produced result. When the size is unchanged, result is copied
+// into the existing rows, so that a view made by SubImage and its parent
+// keep sharing their pixels.
+func storeGrid[T any](data, result [][]T) [][]T {
+	if len(data) != len(result) {
+		return result
+	}
+	for y := range data {
+		if len(data[y]) != len(result[y]) {
+			return result
+		}
+	}
+	for y := range data {
+		copy(data[y], result[y])
+	}
+	return data
+}
+
+// Bounds returns the rectangle covering the whole image.
+func (pbm *PBM) Bounds() Rectangle {
+	return Rect(0, 0, pbm.width, pbm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the image: Set, Invert, filters and any other operation
+// keeping the size of the view change both. Operations that change the size
+// of the view, like Rotate90CW or Crop, give it its own storage.
+func (pbm *PBM) SubImage(r Rectangle) *PBM {
+	r = r.Intersect(pbm.Bounds())
+	return &PBM{data: subGrid(pbm.data, r), width: r.Dx(), height: r.Dy(), magicNumber: pbm.magicNumber}
+}
+
+// Bounds returns the rectangle covering the whole image.
+func (pgm *PGM) Bounds() Rectangle {
+	return Rect(0, 0, pgm.width, pgm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the image: Set, Invert, filters and any other operation
+// keeping the size of the view change both. Operations that change the size
+// of the view, like Rotate90CW or Crop, give it its own storage.
+func (pgm *PGM) SubImage(r Rectangle) *PGM {
+	r = r.Intersect(pgm.Bounds())
+	return &PGM{data: subGrid(pgm.data, r), width: r.Dx(), height: r.Dy(), magicNumber: pgm.magicNumber, max: pgm.max}
+}
+
+// Bounds returns the rectangle covering the whole image.
+func (ppm *PPM) Bounds() Rectangle {
+	return Rect(0, 0, ppm.width, ppm.height)
+}
+
+// SubImage returns a view of the part of the image inside r. The view shares
+// its pixels with the image: Set, Invert, the drawing functions, filters and
+// any other operation keeping the size of the view change both. Operations
+// that change the size of the view, like Rotate90CW or Crop, give it its own
+// storage.
+func (ppm *PPM) SubImage(r Rectangle) *PPM {
+	r = r.Intersect(ppm.Bounds())
+	return &PPM{data: subGrid(ppm.data, r), width: r.Dx(), height: r.Dy(), magicNumber: ppm.magicNumber, max: ppm.max}
+}
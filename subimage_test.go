@@ -0,0 +1,90 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRectIntersect(t *testing.T) {
+	r := Rect(5, 4, 1, 0).Intersect(Rect(2, 2, 10, 10))
+	if r != (Rectangle{Point{2, 2}, Point{5, 4}}) || r.Dx() != 3 || r.Dy() != 2 {
+		t.Fatalf("got %v", r)
+	}
+	if !Rect(0, 0, 2, 2).Intersect(Rect(3, 3, 4, 4)).Empty() {
+		t.Fatal("disjoint rectangles should have an empty intersection")
+	}
+}
+
+func TestSubImageInvert(t *testing.T) {
+	pgm := newTestPGM(4, 4, func(x, y int) uint16 { return 0 })
+	pgm.SubImage(Rect(0, 0, 2, 2)).Invert()
+	if pgm.data[1][1] != 255 || pgm.data[2][2] != 0 {
+		t.Fatalf("got %d and %d, want 255 and 0", pgm.data[1][1], pgm.data[2][2])
+	}
+}
+
+func TestSubImageClippedToBounds(t *testing.T) {
+	pgm := newTestPGM(4, 3, func(x, y int) uint16 { return 0 })
+	view := pgm.SubImage(Rect(2, 1, 10, 10))
+	if w, h := view.Size(); w != 2 || h != 2 {
+		t.Fatalf("size %dx%d, want 2x2", w, h)
+	}
+	if empty := pgm.SubImage(Rect(5, 5, 8, 8)); empty.width != 0 || empty.height != 0 {
+		t.Fatalf("view outside the image: %dx%d", empty.width, empty.height)
+	}
+}
+
+func TestSubImageSameSizeOperationsStayAttached(t *testing.T) {
+	pgm := newTestPGM(4, 4, func(x, y int) uint16 { return uint16(10*y + x) })
+	view := pgm.SubImage(Rect(1, 1, 3, 3))
+	view.Rotate180()
+	if want := [][]uint16{{0, 1, 2, 3}, {10, 22, 21, 13}, {20, 12, 11, 23}, {30, 31, 32, 33}}; !reflect.DeepEqual(pgm.data, want) {
+		t.Fatalf("Rotate180 did not reach the parent: %v", pgm.data)
+	}
+	if err := view.Levels(LevelsOptions{InputWhite: 255, OutputBlack: 100, OutputWhite: 100}); err != nil {
+		t.Fatal(err)
+	}
+	view.Invert()
+	if pgm.data[1][1] != 155 || pgm.data[2][2] != 155 || pgm.data[0][0] != 0 {
+		t.Fatalf("view detached from the parent: %v", pgm.data)
+	}
+}
+
+func TestSubImageResizeDetaches(t *testing.T) {
+	pgm := newTestPGM(4, 4, func(x, y int) uint16 { return 0 })
+	view := pgm.SubImage(Rect(0, 0, 2, 2))
+	if err := view.Resize(3, 3, FilterNearest); err != nil {
+		t.Fatal(err)
+	}
+	view.Invert()
+	if pgm.data[0][0] != 0 {
+		t.Fatal("a view with a new size still writes to the parent")
+	}
+}
+
+func TestSubImagePPMFlop(t *testing.T) {
+	ppm := newTestPPM(4, 4, func(x, y int) Pixel { return Pixel{} })
+	view := ppm.SubImage(Rect(0, 0, 3, 3))
+	view.data[0][0] = Pixel{1, 2, 3}
+	view.Flop()
+	if ppm.data[2][0] != (Pixel{1, 2, 3}) || ppm.data[0][0] != (Pixel{}) {
+		t.Fatalf("flop did not reach the parent: %v", ppm.data[:3])
+	}
+	view.Rotate(90, RotateOptions{Interpolation: Bilinear}, Pixel{})
+	if ppm.data[2][2] != (Pixel{1, 2, 3}) || ppm.data[2][0] != (Pixel{}) {
+		t.Fatalf("rotation did not reach the parent: %v", ppm.data[:3])
+	}
+}
+
+func TestPBMSubImage(t *testing.T) {
+	pbm := newTestPBM(
+		"#...",
+		"....",
+	)
+	view := pbm.SubImage(Rect(0, 0, 2, 2))
+	view.Transpose()
+	view.Rotate90CW()
+	if want := []string{".#..", "...."}; !reflect.DeepEqual(pbmRows(pbm), want) {
+		t.Fatalf("got %v, want %v", pbmRows(pbm), want)
+	}
+}
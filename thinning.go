@@ -0,0 +1,235 @@
+package Netpbm
+
+// neighbours8 returns the 8 neighbours of (x, y) as P2..P9 in the usual
+// thinning notation: north first, then clockwise. Pixels outside the image
+// are white.
+func (pbm *PBM) neighbours8(x, y int) [8]bool {
+	at := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < pbm.width && y < pbm.height && pbm.data[y][x]
+	}
+	return [8]bool{at(x, y-1), at(x+1, y-1), at(x+1, y), at(x+1, y+1), at(x, y+1), at(x-1, y+1), at(x-1, y), at(x-1, y-1)}
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// thin runs a two-pass thinning until no pixel is removed. remove decides, for
+// each black pixel, from its neighbours and the pass number, if it is deleted.
+func (pbm *PBM) thin(remove func(p [8]bool, pass int) bool) {
+	var deleted []Point
+	for changed := true; changed; {
+		changed = false
+		for pass := 0; pass < 2; pass++ {
+			deleted = deleted[:0]
+			for y := 0; y < pbm.height; y++ {
+				for x := 0; x < pbm.width; x++ {
+					if pbm.data[y][x] && remove(pbm.neighbours8(x, y), pass) {
+						deleted = append(deleted, Point{x, y})
+					}
+				}
+			}
+			for _, p := range deleted {
+				pbm.data[p.Y][p.X] = false
+			}
+			if len(deleted) > 0 {
+				changed = true
+			}
+		}
+	}
+}
+
+// ThinZhangSuen reduces the black shapes to one-pixel-wide skeletons with the
+// Zhang-Suen algorithm.
+func (pbm *PBM) ThinZhangSuen() {
+	pbm.thin(func(p [8]bool, pass int) bool {
+		p2, p4, p6, p8 := p[0], p[2], p[4], p[6]
+		b := 0
+		for _, v := range p {
+			b += b2i(v)
+		}
+		if b < 2 || b > 6 {
+			return false
+		}
+		// Number of white to black transitions around the pixel
+		a := 0
+		for i := range p {
+			if !p[i] && p[(i+1)%8] {
+				a++
+			}
+		}
+		if a != 1 {
+			return false
+		}
+		if pass == 0 {
+			return !(p2 && p4 && p6) && !(p4 && p6 && p8)
+		}
+		return !(p2 && p4 && p8) && !(p2 && p6 && p8)
+	})
+}
+
+// ThinGuoHall reduces the black shapes to one-pixel-wide skeletons with the
+// Guo-Hall algorithm, which keeps diagonal lines thinner than Zhang-Suen.
+func (pbm *PBM) ThinGuoHall() {
+	pbm.thin(func(p [8]bool, pass int) bool {
+		p2, p3, p4, p5, p6, p7, p8, p9 := p[0], p[1], p[2], p[3], p[4], p[5], p[6], p[7]
+		c := b2i(!p2 && (p3 || p4)) + b2i(!p4 && (p5 || p6)) + b2i(!p6 && (p7 || p8)) + b2i(!p8 && (p9 || p2))
+		if c != 1 {
+			return false
+		}
+		n1 := b2i(p9 || p2) + b2i(p3 || p4) + b2i(p5 || p6) + b2i(p7 || p8)
+		n2 := b2i(p2 || p3) + b2i(p4 || p5) + b2i(p6 || p7) + b2i(p8 || p9)
+		n := n1
+		if n2 < n {
+			n = n2
+		}
+		if n < 2 || n > 3 {
+			return false
+		}
+		var m bool
+		if pass == 0 {
+			m = (p6 || p7 || !p9) && p8
+		} else {
+			m = (p2 || p3 || !p5) && p4
+		}
+		return !m
+	})
+}
+
+// MedialAxis computes the medial axis transform of the black shapes: the
+// centers of the maximal disks that fit inside them. It returns the axis as a
+// PBM image and, for each axis pixel, the radius of its disk (the distance to
+// the nearest white pixel, approximated with a 3-4 chamfer metric), indexed
+// [y][x] and zero elsewhere.
+func (pbm *PBM) MedialAxis() (*PBM, [][]float64) {
+	w, h := pbm.width, pbm.height
+	d := chamferDistance(pbm.data, w, h, 3, 4, true)
+	axis := &PBM{data: newGrid[bool](w, h), width: w, height: h, magicNumber: pbm.magicNumber}
+	radius := newGrid[float64](w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if d[y][x] == 0 {
+				continue
+			}
+			// Center of a maximal disk: no neighbour's disk contains it
+			maximal := true
+			for _, n := range Connectivity8.offsets() {
+				nx, ny := x+n.X, y+n.Y
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				step := 3
+				if n.X != 0 && n.Y != 0 {
+					step = 4
+				}
+				if d[ny][nx] >= d[y][x]+step {
+					maximal = false
+					break
+				}
+			}
+			if maximal {
+				axis.data[y][x] = true
+				radius[y][x] = float64(d[y][x]) / 3
+			}
+		}
+	}
+	return axis, radius
+}
+
+// isEndPoint reports whether a black pixel ends a line: its black neighbours
+// form a single run of at most three pixels.
+func (pbm *PBM) isEndPoint(x, y int) bool {
+	p := pbm.neighbours8(x, y)
+	b, a := 0, 0
+	for i := range p {
+		b += b2i(p[i])
+		if !p[i] && p[(i+1)%8] {
+			a++
+		}
+	}
+	return b >= 1 && b <= 3 && a == 1
+}
+
+// isJunction reports whether at least three branches meet at a black pixel:
+// its black neighbours form three runs or more.
+func (pbm *PBM) isJunction(x, y int) bool {
+	p := pbm.neighbours8(x, y)
+	a := 0
+	for i := range p {
+		if !p[i] && p[(i+1)%8] {
+			a++
+		}
+	}
+	return a >= 3
+}
+
+// Prune removes the spurs of a skeleton, branches shorter than length pixels,
+// with the classic peel-and-regrow method: end points are peeled length
+// times, then the surviving branches grow back by length pixels inside the
+// original skeleton. Components without any junction have no spur and are
+// kept whole.
+func (pbm *PBM) Prune(length int) {
+	original := newGrid[bool](pbm.width, pbm.height)
+	for y := range original {
+		copy(original[y], pbm.data[y])
+	}
+	labels, count := labelGrid(pbm.width, pbm.height, Connectivity8, func(x, y int) bool { return original[y][x] })
+	branched := make([]bool, count+1)
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if original[y][x] && pbm.isJunction(x, y) {
+				branched[labels[y][x]] = true
+			}
+		}
+	}
+
+	var ends []Point
+	for i := 0; i < length; i++ {
+		ends = ends[:0]
+		for y := 0; y < pbm.height; y++ {
+			for x := 0; x < pbm.width; x++ {
+				if pbm.data[y][x] && pbm.isEndPoint(x, y) {
+					ends = append(ends, Point{x, y})
+				}
+			}
+		}
+		for _, p := range ends {
+			pbm.data[p.Y][p.X] = false
+		}
+	}
+
+	// Grow the remaining branches back from their end points
+	var frontier []Point
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if pbm.data[y][x] && pbm.isEndPoint(x, y) {
+				frontier = append(frontier, Point{x, y})
+			}
+		}
+	}
+	for i := 0; i < length && len(frontier) > 0; i++ {
+		var next []Point
+		for _, p := range frontier {
+			for _, n := range Connectivity8.offsets() {
+				q := Point{p.X + n.X, p.Y + n.Y}
+				if q.X >= 0 && q.Y >= 0 && q.X < pbm.width && q.Y < pbm.height && original[q.Y][q.X] && !pbm.data[q.Y][q.X] {
+					pbm.data[q.Y][q.X] = true
+					next = append(next, q)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// Lines without a junction may have been peeled away entirely
+	for y := 0; y < pbm.height; y++ {
+		for x := 0; x < pbm.width; x++ {
+			if original[y][x] && !branched[labels[y][x]] {
+				pbm.data[y][x] = true
+			}
+		}
+	}
+}
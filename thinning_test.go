@@ -0,0 +1,108 @@
+package Netpbm
+
+import "testing"
+
+func countBlack(pbm *PBM) int {
+	n := 0
+	for y := range pbm.data {
+		for _, v := range pbm.data[y] {
+			if v {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// barPBM returns a 3-pixel-thick horizontal bar with a white margin.
+func barPBM() *PBM {
+	return newTestPBM(
+		"............",
+		".##########.",
+		".##########.",
+		".##########.",
+		"............",
+	)
+}
+
+func checkSkeletonOfBar(t *testing.T, name string, pbm *PBM) {
+	t.Helper()
+	for x := 0; x < pbm.width; x++ {
+		n := 0
+		for y := 0; y < pbm.height; y++ {
+			if pbm.data[y][x] {
+				n++
+			}
+		}
+		if n > 1 {
+			t.Fatalf("%s: column %d is %d pixels thick: %v", name, x, n, pbmRows(pbm))
+		}
+	}
+	if !pbm.data[2][5] || !pbm.data[2][6] {
+		t.Fatalf("%s: the skeleton should run along the middle row: %v", name, pbmRows(pbm))
+	}
+	labels, count := labelGrid(pbm.width, pbm.height, Connectivity8, func(x, y int) bool { return pbm.data[y][x] })
+	if count != 1 {
+		t.Fatalf("%s: skeleton split into %d parts (%v)", name, count, labels)
+	}
+}
+
+func TestThinning(t *testing.T) {
+	zs := barPBM()
+	zs.ThinZhangSuen()
+	checkSkeletonOfBar(t, "Zhang-Suen", zs)
+	gh := barPBM()
+	gh.ThinGuoHall()
+	checkSkeletonOfBar(t, "Guo-Hall", gh)
+}
+
+func TestMedialAxis(t *testing.T) {
+	pbm := newTestPBM(
+		".......",
+		".#####.",
+		".#####.",
+		".#####.",
+		".#####.",
+		".#####.",
+		".......",
+	)
+	axis, radius := pbm.MedialAxis()
+	if !axis.data[3][3] {
+		t.Fatalf("center missing from the axis: %v", pbmRows(axis))
+	}
+	if radius[3][3] != 3 {
+		t.Fatalf("center radius %g, want 3", radius[3][3])
+	}
+	if axis.data[0][0] || radius[0][0] != 0 {
+		t.Fatal("white pixel on the axis")
+	}
+}
+
+func TestPruneKeepsLines(t *testing.T) {
+	pbm := newTestPBM(
+		".......",
+		".#####.",
+		".......",
+	)
+	pbm.Prune(3)
+	if got := countBlack(pbm); got != 5 {
+		t.Fatalf("isolated line: got %d pixels, want 5", got)
+	}
+}
+
+func TestPruneRemovesSpur(t *testing.T) {
+	pbm := newTestPBM(
+		".................",
+		".###############.",
+		"........#........",
+		"........#........",
+		".................",
+	)
+	pbm.Prune(3)
+	if pbm.data[2][8] || pbm.data[3][8] {
+		t.Fatal("spur was not removed")
+	}
+	if got := countBlack(pbm); got != 15 {
+		t.Fatalf("main branch: got %d pixels, want 15", got)
+	}
+}
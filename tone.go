@@ -0,0 +1,277 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// LevelsOptions describes a levels adjustment. Samples at or below InputBlack
+// map to OutputBlack, samples at or above InputWhite map to OutputWhite, and
+// Gamma bends the midtones in between. All values use the image's maxval.
+type LevelsOptions struct {
+	InputBlack, InputWhite   int
+	OutputBlack, OutputWhite int
+	Gamma                    float64
+}
+
+// CurvePoint is a control point of a tone curve, with both coordinates in
+// [0, maxval].
+type CurvePoint struct {
+	In, Out float64
+}
+
+// GammaLUT builds a lookup table applying a gamma correction: each sample v
+// becomes maxval * (v/maxval)^(1/gamma).
+func GammaLUT(max int, gamma float64) []uint16 {
+	return buildLUT(max, func(v float64) float64 {
+		if gamma <= 0 {
+			return v
+		}
+		return math.Pow(v, 1/gamma)
+	})
+}
+
+// BrightnessContrastLUT builds a lookup table shifting brightness and scaling
+// contrast around the midpoint. Both amounts are in [-1, 1], 0 meaning no
+// change.
+func BrightnessContrastLUT(max int, brightness, contrast float64) []uint16 {
+	factor := math.Tan((contrast + 1) * math.Pi / 4)
+	return buildLUT(max, func(v float64) float64 {
+		return (v-0.5)*factor + 0.5 + brightness
+	})
+}
+
+// LevelsLUT builds a lookup table for a levels adjustment.
+func LevelsLUT(max int, opts LevelsOptions) []uint16 {
+	gamma := opts.Gamma
+	if gamma <= 0 {
+		gamma = 1
+	}
+	span := float64(opts.InputWhite - opts.InputBlack)
+	lut := make([]uint16, max+1)
+	for i := range lut {
+		t := 0.0
+		if span > 0 {
+			t = clampUnit((float64(i) - float64(opts.InputBlack)) / span)
+		} else if i >= opts.InputWhite {
+			t = 1
+		}
+		t = math.Pow(t, 1/gamma)
+		out := float64(opts.OutputBlack) + t*float64(opts.OutputWhite-opts.OutputBlack)
+		lut[i] = uint16(clamp(int(math.Round(out)), 0, max))
+	}
+	return lut
+}
+
+// CurveLUT builds a lookup table from a tone curve through the given control
+// points, interpolated with a monotone cubic spline so the curve never
+// overshoots between points. Inputs outside the points are held constant.
+func CurveLUT(max int, points []CurvePoint) ([]uint16, error) {
+	if err := checkMaxValue(max); err != nil {
+		return nil, err
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("a curve needs at least 2 points")
+	}
+	pts := make([]CurvePoint, len(points))
+	copy(pts, points)
+	sort.Slice(pts, func(i, j int) bool { return pts[i].In < pts[j].In })
+	for i := 1; i < len(pts); i++ {
+		if pts[i].In == pts[i-1].In {
+			return nil, fmt.Errorf("duplicate curve point at input %g", pts[i].In)
+		}
+	}
+
+	spline := newMonotoneSpline(pts)
+	lut := make([]uint16, max+1)
+	for i := range lut {
+		lut[i] = uint16(clamp(int(math.Round(spline.at(float64(i)))), 0, max))
+	}
+	return lut, nil
+}
+
+// buildLUT tabulates a transfer function working on samples normalized to
+// [0, 1].
+func buildLUT(max int, f func(float64) float64) []uint16 {
+	lut := make([]uint16, max+1)
+	for i := range lut {
+		v := 0.0
+		if max > 0 {
+			v = float64(i) / float64(max)
+		}
+		lut[i] = unitToSample(f(v), max)
+	}
+	return lut
+}
+
+// monotoneSpline is a Fritsch-Carlson monotone cubic Hermite spline.
+type monotoneSpline struct {
+	points   []CurvePoint
+	tangents []float64
+}
+
+func newMonotoneSpline(points []CurvePoint) *monotoneSpline {
+	n := len(points)
+	slopes := make([]float64, n-1)
+	for i := range slopes {
+		slopes[i] = (points[i+1].Out - points[i].Out) / (points[i+1].In - points[i].In)
+	}
+
+	tangents := make([]float64, n)
+	tangents[0] = slopes[0]
+	tangents[n-1] = slopes[n-2]
+	for i := 1; i < n-1; i++ {
+		if slopes[i-1]*slopes[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (slopes[i-1] + slopes[i]) / 2
+		}
+	}
+
+	// Limit the tangents so the curve stays monotone
+	for i, s := range slopes {
+		if s == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		a, b := tangents[i]/s, tangents[i+1]/s
+		if h := a*a + b*b; h > 9 {
+			t := 3 / math.Sqrt(h)
+			tangents[i] = t * a * s
+			tangents[i+1] = t * b * s
+		}
+	}
+	return &monotoneSpline{points: points, tangents: tangents}
+}
+
+func (s *monotoneSpline) at(x float64) float64 {
+	pts := s.points
+	if x <= pts[0].In {
+		return pts[0].Out
+	}
+	if x >= pts[len(pts)-1].In {
+		return pts[len(pts)-1].Out
+	}
+	i := sort.Search(len(pts), func(i int) bool { return pts[i].In > x }) - 1
+	h := pts[i+1].In - pts[i].In
+	t := (x - pts[i].In) / h
+	t2, t3 := t*t, t*t*t
+	return (2*t3-3*t2+1)*pts[i].Out + (t3-2*t2+t)*h*s.tangents[i] +
+		(-2*t3+3*t2)*pts[i+1].Out + (t3-t2)*h*s.tangents[i+1]
+}
+
+// ApplyLUT replaces every sample v with lut[v]. The table must have maxval+1
+// entries.
+func (pgm *PGM) ApplyLUT(lut []uint16) error {
+	if len(lut) != pgm.max+1 {
+		return fmt.Errorf("lookup table needs %d entries, got %d", pgm.max+1, len(lut))
+	}
+	for y := range pgm.data {
+		for x, v := range pgm.data[y] {
+			if int(v) > pgm.max {
+				v = uint16(pgm.max)
+			}
+			pgm.data[y][x] = lut[v]
+		}
+	}
+	return nil
+}
+
+// Gamma applies a gamma correction to the image. It fails if the image's
+// maxval is invalid.
+func (pgm *PGM) Gamma(gamma float64) error {
+	if err := checkMaxValue(pgm.max); err != nil {
+		return err
+	}
+	return pgm.ApplyLUT(GammaLUT(pgm.max, gamma))
+}
+
+// BrightnessContrast adjusts brightness and contrast, both in [-1, 1].
+func (pgm *PGM) BrightnessContrast(brightness, contrast float64) error {
+	if err := checkMaxValue(pgm.max); err != nil {
+		return err
+	}
+	return pgm.ApplyLUT(BrightnessContrastLUT(pgm.max, brightness, contrast))
+}
+
+// Levels applies a levels adjustment to the image.
+func (pgm *PGM) Levels(opts LevelsOptions) error {
+	if err := checkMaxValue(pgm.max); err != nil {
+		return err
+	}
+	return pgm.ApplyLUT(LevelsLUT(pgm.max, opts))
+}
+
+// Curves applies a tone curve defined by control points to the image.
+func (pgm *PGM) Curves(points []CurvePoint) error {
+	lut, err := CurveLUT(pgm.max, points)
+	if err != nil {
+		return err
+	}
+	return pgm.ApplyLUT(lut)
+}
+
+// ApplyLUT replaces every sample with its entry in the lookup table of its
+// channel. Each table must have maxval+1 entries; a nil table leaves the
+// channel unchanged.
+func (ppm *PPM) ApplyLUT(r, g, b []uint16) error {
+	luts := [3][]uint16{r, g, b}
+	for _, lut := range luts {
+		if lut != nil && len(lut) != ppm.max+1 {
+			return fmt.Errorf("lookup table needs %d entries, got %d", ppm.max+1, len(lut))
+		}
+	}
+	for y := range ppm.data {
+		for x := range ppm.data[y] {
+			for c, lut := range luts {
+				if lut == nil {
+					continue
+				}
+				v := Channel(c).get(ppm.data[y][x])
+				if int(v) > ppm.max {
+					v = uint16(ppm.max)
+				}
+				Channel(c).set(&ppm.data[y][x], lut[v])
+			}
+		}
+	}
+	return nil
+}
+
+// Gamma applies the same gamma correction to the three channels. It fails
+// if the image's maxval is invalid.
+func (ppm *PPM) Gamma(gamma float64) error {
+	if err := checkMaxValue(ppm.max); err != nil {
+		return err
+	}
+	lut := GammaLUT(ppm.max, gamma)
+	return ppm.ApplyLUT(lut, lut, lut)
+}
+
+// BrightnessContrast adjusts brightness and contrast, both in [-1, 1].
+func (ppm *PPM) BrightnessContrast(brightness, contrast float64) error {
+	if err := checkMaxValue(ppm.max); err != nil {
+		return err
+	}
+	lut := BrightnessContrastLUT(ppm.max, brightness, contrast)
+	return ppm.ApplyLUT(lut, lut, lut)
+}
+
+// Levels applies the same levels adjustment to the three channels.
+func (ppm *PPM) Levels(opts LevelsOptions) error {
+	if err := checkMaxValue(ppm.max); err != nil {
+		return err
+	}
+	lut := LevelsLUT(ppm.max, opts)
+	return ppm.ApplyLUT(lut, lut, lut)
+}
+
+// Curves applies the same tone curve to the three channels.
+func (ppm *PPM) Curves(points []CurvePoint) error {
+	lut, err := CurveLUT(ppm.max, points)
+	if err != nil {
+		return err
+	}
+	return ppm.ApplyLUT(lut, lut, lut)
+}
@@ -0,0 +1,101 @@
+package Netpbm
+
+import "testing"
+
+func checkMonotone(t *testing.T, name string, lut []uint16) {
+	t.Helper()
+	for i := 1; i < len(lut); i++ {
+		if lut[i] < lut[i-1] {
+			t.Fatalf("%s: lut[%d] = %d < lut[%d] = %d", name, i, lut[i], i-1, lut[i-1])
+		}
+	}
+}
+
+func TestLUTsAreMonotone(t *testing.T) {
+	for _, gamma := range []float64{0.4, 1, 2.2} {
+		lut := GammaLUT(255, gamma)
+		checkMonotone(t, "gamma", lut)
+		if lut[0] != 0 || lut[255] != 255 {
+			t.Errorf("gamma %g: endpoints %d, %d", gamma, lut[0], lut[255])
+		}
+	}
+	checkMonotone(t, "brightness/contrast", BrightnessContrastLUT(255, 0.2, 0.5))
+	checkMonotone(t, "levels", LevelsLUT(255, LevelsOptions{InputBlack: 20, InputWhite: 200, OutputWhite: 255, Gamma: 1.5}))
+
+	// The control points would make a naive cubic spline overshoot.
+	curve, err := CurveLUT(255, []CurvePoint{{0, 0}, {100, 10}, {110, 240}, {255, 255}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMonotone(t, "curve", curve)
+	if curve[100] != 10 || curve[110] != 240 {
+		t.Errorf("curve misses its control points: %d, %d", curve[100], curve[110])
+	}
+}
+
+func TestGammaIdentity(t *testing.T) {
+	for i, v := range GammaLUT(255, 1) {
+		if int(v) != i {
+			t.Fatalf("lut[%d] = %d", i, v)
+		}
+	}
+	for i, v := range BrightnessContrastLUT(255, 0, 0) {
+		if int(v) != i {
+			t.Fatalf("brightness/contrast lut[%d] = %d", i, v)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	pgm := newTestPGM(4, 1, func(x, y int) uint16 { return []uint16{0, 50, 150, 250}[x] })
+	if err := pgm.Levels(LevelsOptions{InputBlack: 50, InputWhite: 150, OutputBlack: 10, OutputWhite: 210}); err != nil {
+		t.Fatal(err)
+	}
+	for x, want := range []uint16{10, 10, 210, 210} {
+		if got := pgm.data[0][x]; got != want {
+			t.Errorf("x=%d: got %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestCurveLUTErrors(t *testing.T) {
+	if _, err := CurveLUT(255, []CurvePoint{{0, 0}}); err == nil {
+		t.Error("single point accepted")
+	}
+	if _, err := CurveLUT(255, []CurvePoint{{10, 0}, {10, 20}}); err == nil {
+		t.Error("duplicate input accepted")
+	}
+}
+
+func TestApplyLUTErrors(t *testing.T) {
+	pgm := newTestPGM(1, 1, func(x, y int) uint16 { return 3 })
+	if err := pgm.ApplyLUT(make([]uint16, 10)); err == nil {
+		t.Error("short table accepted")
+	}
+	pgm.max = 0
+	if err := pgm.Gamma(2); err == nil {
+		t.Error("Gamma accepted a maxval of 0")
+	}
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{1, 2, 3} })
+	if err := ppm.ApplyLUT(nil, make([]uint16, 3), nil); err == nil {
+		t.Error("short table accepted")
+	}
+	ppm.max = 0
+	if err := ppm.Levels(LevelsOptions{InputWhite: 1}); err == nil {
+		t.Error("Levels accepted a maxval of 0")
+	}
+}
+
+func TestPPMApplyLUTPerChannel(t *testing.T) {
+	ppm := newTestPPM(1, 1, func(x, y int) Pixel { return Pixel{10, 20, 30} })
+	inverse := make([]uint16, 256)
+	for i := range inverse {
+		inverse[i] = uint16(255 - i)
+	}
+	if err := ppm.ApplyLUT(inverse, nil, inverse); err != nil {
+		t.Fatal(err)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{245, 20, 225}) {
+		t.Fatalf("got %v, want {245 20 225}", got)
+	}
+}
@@ -0,0 +1,116 @@
+package Netpbm
+
+// newGrid allocates a width x height grid indexed [y][x].
+func newGrid[T any](width, height int) [][]T {
+	grid := make([][]T, height)
+	for y := range grid {
+		grid[y] = make([]T, width)
+	}
+	return grid
+}
+
+// rotate90CW returns the grid rotated 90° clockwise. The result is height
+// pixels wide and width pixels high.
+func rotate90CW[T any](data [][]T, width, height int) [][]T {
+	out := newGrid[T](height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out[x][height-1-y] = data[y][x]
+		}
+	}
+	return out
+}
+
+// rotate90CCW returns the grid rotated 90° counterclockwise.
+func rotate90CCW[T any](data [][]T, width, height int) [][]T {
+	out := newGrid[T](height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out[width-1-x][y] = data[y][x]
+		}
+	}
+	return out
+}
+
+// rotate180 returns the grid rotated by 180°.
+func rotate180[T any](data [][]T, width, height int) [][]T {
+	out := newGrid[T](width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out[height-1-y][width-1-x] = data[y][x]
+		}
+	}
+	return out
+}
+
+// transpose returns the grid mirrored across its main diagonal.
+func transpose[T any](data [][]T, width, height int) [][]T {
+	out := newGrid[T](height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out[x][y] = data[y][x]
+		}
+	}
+	return out
+}
+
+// transverse returns the grid mirrored across its anti-diagonal.
+func transverse[T any](data [][]T, width, height int) [][]T {
+	out := newGrid[T](height, width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			out[width-1-x][height-1-y] = data[y][x]
+		}
+	}
+	return out
+}
+
+// placeGrid returns a newWidth x newHeight grid filled with fill, with the
+// source grid copied so that its top-left corner lands on (offsetX, offsetY).
+// Parts falling outside the new grid are dropped.
+func placeGrid[T any](data [][]T, width, height, newWidth, newHeight, offsetX, offsetY int, fill T) [][]T {
+	out := newGrid[T](newWidth, newHeight)
+	for y := 0; y < newHeight; y++ {
+		sy := y - offsetY
+		for x := 0; x < newWidth; x++ {
+			sx := x - offsetX
+			if sx >= 0 && sy >= 0 && sx < width && sy < height {
+				out[y][x] = data[sy][sx]
+			} else {
+				out[y][x] = fill
+			}
+		}
+	}
+	return out
+}
+
+// autoCropBounds returns the smallest rectangle [x0, x1) x [y0, y1) holding
+// every pixel that differs from the top-left corner. ok is false when the grid
+// is uniform.
+func autoCropBounds[T comparable](data [][]T, width, height int) (x0, y0, x1, y1 int, ok bool) {
+	if width == 0 || height == 0 {
+		return 0, 0, 0, 0, false
+	}
+	background := data[0][0]
+	x0, y0, x1, y1 = width, height, 0, 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if data[y][x] == background {
+				continue
+			}
+			if x < x0 {
+				x0 = x
+			}
+			if x >= x1 {
+				x1 = x + 1
+			}
+			if y < y0 {
+				y0 = y
+			}
+			if y >= y1 {
+				y1 = y + 1
+			}
+		}
+	}
+	return x0, y0, x1, y1, x1 > x0
+}
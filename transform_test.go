@@ -0,0 +1,86 @@
+package Netpbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Samples of a 3x2 image: row y holds 10*y + x.
+func newTransformPGM() *PGM {
+	return newTestPGM(3, 2, func(x, y int) uint16 { return uint16(10*y + x) })
+}
+
+func TestPGMRotationsNonSquare(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(*PGM)
+		want [][]uint16
+	}{
+		{"Rotate90CW", (*PGM).Rotate90CW, [][]uint16{{10, 0}, {11, 1}, {12, 2}}},
+		{"Rotate90CCW", (*PGM).Rotate90CCW, [][]uint16{{2, 12}, {1, 11}, {0, 10}}},
+		{"Rotate180", (*PGM).Rotate180, [][]uint16{{12, 11, 10}, {2, 1, 0}}},
+		{"Rotate270CW", (*PGM).Rotate270CW, [][]uint16{{2, 12}, {1, 11}, {0, 10}}},
+		{"Transpose", (*PGM).Transpose, [][]uint16{{0, 10}, {1, 11}, {2, 12}}},
+		{"Transverse", (*PGM).Transverse, [][]uint16{{12, 2}, {11, 1}, {10, 0}}},
+	}
+	for _, tt := range tests {
+		pgm := newTransformPGM()
+		tt.op(pgm)
+		if !reflect.DeepEqual(pgm.data, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, pgm.data, tt.want)
+		}
+		if w, h := pgm.Size(); w != len(tt.want[0]) || h != len(tt.want) {
+			t.Errorf("%s: size %dx%d", tt.name, w, h)
+		}
+	}
+}
+
+func TestRotateFourTimesIsIdentity(t *testing.T) {
+	pgm := newTransformPGM()
+	for i := 0; i < 4; i++ {
+		pgm.Rotate90CW()
+	}
+	if !reflect.DeepEqual(pgm.data, newTransformPGM().data) {
+		t.Fatalf("got %v", pgm.data)
+	}
+	pgm.Transpose()
+	pgm.Transpose()
+	if !reflect.DeepEqual(pgm.data, newTransformPGM().data) {
+		t.Fatalf("double transpose: got %v", pgm.data)
+	}
+}
+
+func TestPPMRotate90CWNonSquare(t *testing.T) {
+	ppm := newTestPPM(3, 2, func(x, y int) Pixel { return Pixel{uint16(x), uint16(y), 0} })
+	ppm.Rotate90CW()
+	if ppm.width != 2 || ppm.height != 3 {
+		t.Fatalf("size %dx%d, want 2x3", ppm.width, ppm.height)
+	}
+	if got := ppm.data[0][0]; got != (Pixel{0, 1, 0}) {
+		t.Errorf("top-left: got %v", got)
+	}
+	if got := ppm.data[2][1]; got != (Pixel{2, 0, 0}) {
+		t.Errorf("bottom-right: got %v", got)
+	}
+	ppm.Transverse()
+	if ppm.width != 3 || ppm.height != 2 {
+		t.Fatalf("size %dx%d, want 3x2", ppm.width, ppm.height)
+	}
+}
+
+func TestPBMTranspose(t *testing.T) {
+	pbm := newTestPBM(
+		"##.",
+		"...",
+	)
+	pbm.Transpose()
+	want := []string{"#.", "#.", ".."}
+	if got := pbmRows(pbm); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	pbm.Rotate90CCW()
+	want = []string{"...", "##."}
+	if got := pbmRows(pbm); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
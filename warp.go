@@ -0,0 +1,226 @@
+package Netpbm
+
+import (
+	"fmt"
+	"math"
+)
+
+// PointF is a point with real coordinates. Pixel (x, y) is centered on
+// PointF{x, y}.
+type PointF struct {
+	X, Y float64
+}
+
+// Affine is a 2x3 affine matrix [a b c; d e f] mapping (x, y) to
+// (a*x + b*y + c, d*x + e*y + f).
+type Affine [6]float64
+
+// Homography is a 3x3 projective matrix stored in row-major order.
+type Homography [9]float64
+
+// WarpOptions controls a warp.
+type WarpOptions struct {
+	// Interpolation used to read the source pixels.
+	Interpolation Interpolation
+	// Width and Height of the output image. Zero keeps the source size.
+	Width, Height int
+}
+
+// IdentityAffine returns the affine matrix that leaves points unchanged.
+func IdentityAffine() Affine {
+	return Affine{1, 0, 0, 0, 1, 0}
+}
+
+// TranslateAffine returns a translation by (tx, ty).
+func TranslateAffine(tx, ty float64) Affine {
+	return Affine{1, 0, tx, 0, 1, ty}
+}
+
+// ScaleAffine returns a scaling around the origin.
+func ScaleAffine(sx, sy float64) Affine {
+	return Affine{sx, 0, 0, 0, sy, 0}
+}
+
+// RotateAffine returns a rotation around the origin. As with Rotate, positive
+// angles turn the image counterclockwise on screen.
+func RotateAffine(degrees float64) Affine {
+	theta := degrees * math.Pi / 180
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	return Affine{cos, sin, 0, -sin, cos, 0}
+}
+
+// ShearAffine returns a shear: x moves by shx*y and y moves by shy*x.
+func ShearAffine(shx, shy float64) Affine {
+	return Affine{1, shx, 0, shy, 1, 0}
+}
+
+// Multiply returns the matrix applying n first, then m.
+func (m Affine) Multiply(n Affine) Affine {
+	return Affine{
+		m[0]*n[0] + m[1]*n[3], m[0]*n[1] + m[1]*n[4], m[0]*n[2] + m[1]*n[5] + m[2],
+		m[3]*n[0] + m[4]*n[3], m[3]*n[1] + m[4]*n[4], m[3]*n[2] + m[4]*n[5] + m[5],
+	}
+}
+
+// Apply maps a point through the matrix.
+func (m Affine) Apply(p PointF) PointF {
+	return PointF{m[0]*p.X + m[1]*p.Y + m[2], m[3]*p.X + m[4]*p.Y + m[5]}
+}
+
+// Invert returns the inverse matrix.
+func (m Affine) Invert() (Affine, error) {
+	det := m[0]*m[4] - m[1]*m[3]
+	if math.Abs(det) < 1e-12 {
+		return Affine{}, fmt.Errorf("affine matrix is not invertible")
+	}
+	a, b, d, e := m[4]/det, -m[1]/det, -m[3]/det, m[0]/det
+	return Affine{a, b, -(a*m[2] + b*m[5]), d, e, -(d*m[2] + e*m[5])}, nil
+}
+
+// Homography returns the affine matrix as a projective one.
+func (m Affine) Homography() Homography {
+	return Homography{m[0], m[1], m[2], m[3], m[4], m[5], 0, 0, 1}
+}
+
+// Multiply returns the matrix applying n first, then h.
+func (h Homography) Multiply(n Homography) Homography {
+	var out Homography
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				out[r*3+c] += h[r*3+k] * n[k*3+c]
+			}
+		}
+	}
+	return out
+}
+
+// Apply maps a point through the matrix. ok is false when the point goes to
+// infinity.
+func (h Homography) Apply(p PointF) (PointF, bool) {
+	w := h[6]*p.X + h[7]*p.Y + h[8]
+	if math.Abs(w) < 1e-12 {
+		return PointF{}, false
+	}
+	return PointF{(h[0]*p.X + h[1]*p.Y + h[2]) / w, (h[3]*p.X + h[4]*p.Y + h[5]) / w}, true
+}
+
+// Invert returns the inverse matrix.
+func (h Homography) Invert() (Homography, error) {
+	cof := Homography{
+		h[4]*h[8] - h[5]*h[7], h[2]*h[7] - h[1]*h[8], h[1]*h[5] - h[2]*h[4],
+		h[5]*h[6] - h[3]*h[8], h[0]*h[8] - h[2]*h[6], h[2]*h[3] - h[0]*h[5],
+		h[3]*h[7] - h[4]*h[6], h[1]*h[6] - h[0]*h[7], h[0]*h[4] - h[1]*h[3],
+	}
+	det := h[0]*cof[0] + h[1]*cof[3] + h[2]*cof[6]
+	if math.Abs(det) < 1e-12 {
+		return Homography{}, fmt.Errorf("homography is not invertible")
+	}
+	for i := range cof {
+		cof[i] /= det
+	}
+	return cof, nil
+}
+
+// HomographyFromPoints computes the homography mapping each of the four source
+// points onto the matching destination point.
+func HomographyFromPoints(src, dst [4]PointF) (Homography, error) {
+	// 8x8 linear system: h[8] is fixed to 1
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := src[i].X, src[i].Y
+		u, v := dst[i].X, dst[i].Y
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -x * u, -y * u, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -x * v, -y * v, v}
+	}
+
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for row := col + 1; row < 8; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return Homography{}, fmt.Errorf("degenerate point configuration")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for row := 0; row < 8; row++ {
+			if row == col {
+				continue
+			}
+			f := a[row][col] / a[col][col]
+			for k := col; k < 9; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+		}
+	}
+
+	var h Homography
+	for i := 0; i < 8; i++ {
+		h[i] = a[i][8] / a[i][i]
+	}
+	h[8] = 1
+	return h, nil
+}
+
+// warpMapping returns the output size and the inverse mapping of a warp.
+func warpMapping(h Homography, width, height int, opts WarpOptions) (int, int, pointMapping, error) {
+	inverse, err := h.Invert()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	outWidth, outHeight := opts.Width, opts.Height
+	if outWidth <= 0 {
+		outWidth = width
+	}
+	if outHeight <= 0 {
+		outHeight = height
+	}
+	mapping := func(x, y float64) (float64, float64, bool) {
+		p, ok := inverse.Apply(PointF{x, y})
+		return p.X, p.Y, ok
+	}
+	return outWidth, outHeight, mapping, nil
+}
+
+// WarpPerspective transforms the PGM image with a homography mapping source
+// coordinates to output coordinates. Each output pixel is read back from the
+// source through the inverse matrix; pixels with no source get background.
+func (pgm *PGM) WarpPerspective(h Homography, opts WarpOptions, background uint16) error {
+	w, ht, mapping, err := warpMapping(h, pgm.width, pgm.height, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Interpolation == NearestNeighbor {
+		pgm.data = storeGrid(pgm.data, remapNearest(pgm.data, pgm.width, pgm.height, w, ht, mapping, background))
+	} else {
+		pgm.data = storeGrid(pgm.data, remapPlane(pgm.data, pgm.width, pgm.height, w, ht, mapping, opts.Interpolation, background, pgm.max))
+	}
+	pgm.width, pgm.height = w, ht
+	return nil
+}
+
+// WarpAffine transforms the PGM image with an affine matrix mapping source
+// coordinates to output coordinates.
+func (pgm *PGM) WarpAffine(m Affine, opts WarpOptions, background uint16) error {
+	return pgm.WarpPerspective(m.Homography(), opts, background)
+}
+
+// WarpPerspective transforms the PPM image with a homography mapping source
+// coordinates to output coordinates. Each output pixel is read back from the
+// source through the inverse matrix; pixels with no source get background.
+func (ppm *PPM) WarpPerspective(h Homography, opts WarpOptions, background Pixel) error {
+	w, ht, mapping, err := warpMapping(h, ppm.width, ppm.height, opts)
+	if err != nil {
+		return err
+	}
+	remapPPM(ppm, w, ht, mapping, opts.Interpolation, background)
+	return nil
+}
+
+// WarpAffine transforms the PPM image with an affine matrix mapping source
+// coordinates to output coordinates.
+func (ppm *PPM) WarpAffine(m Affine, opts WarpOptions, background Pixel) error {
+	return ppm.WarpPerspective(m.Homography(), opts, background)
+}
@@ -0,0 +1,84 @@
+package Netpbm
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func closePoint(a, b PointF) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestHomographyFromPoints(t *testing.T) {
+	src := [4]PointF{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	dst := [4]PointF{{2, 1}, {12, 3}, {9, 14}, {-1, 8}}
+	h, err := HomographyFromPoints(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range src {
+		got, ok := h.Apply(src[i])
+		if !ok || !closePoint(got, dst[i]) {
+			t.Errorf("point %d: got %v, want %v", i, got, dst[i])
+		}
+	}
+	inverse, err := h.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range dst {
+		if got, _ := inverse.Apply(dst[i]); !closePoint(got, src[i]) {
+			t.Errorf("inverse point %d: got %v, want %v", i, got, src[i])
+		}
+	}
+
+	collinear := [4]PointF{{0, 0}, {1, 1}, {2, 2}, {3, 3}}
+	if _, err := HomographyFromPoints(collinear, dst); err == nil {
+		t.Error("collinear points accepted")
+	}
+}
+
+func TestAffineCompose(t *testing.T) {
+	m := TranslateAffine(5, -2).Multiply(RotateAffine(90)).Multiply(ScaleAffine(2, 3))
+	// Scale (1, 1) to (2, 3), rotate to (3, -2), then translate.
+	if got := m.Apply(PointF{1, 1}); !closePoint(got, PointF{8, -4}) {
+		t.Fatalf("got %v, want {8 -4}", got)
+	}
+	inverse, err := m.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := inverse.Multiply(m); !closePoint(PointF{got[0], got[4]}, PointF{1, 1}) ||
+		math.Abs(got[1])+math.Abs(got[2])+math.Abs(got[3])+math.Abs(got[5]) > 1e-9 {
+		t.Fatalf("m^-1 m = %v", got)
+	}
+	if _, err := ScaleAffine(0, 1).Invert(); err == nil {
+		t.Error("singular matrix inverted")
+	}
+}
+
+func TestWarpAffineTranslate(t *testing.T) {
+	pgm := newTestPGM(3, 2, func(x, y int) uint16 { return uint16(10*y + x + 1) })
+	if err := pgm.WarpAffine(TranslateAffine(1, 1), WarpOptions{Interpolation: Bilinear, Width: 4}, 99); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]uint16{{99, 99, 99, 99}, {99, 1, 2, 3}}
+	if !reflect.DeepEqual(pgm.data, want) {
+		t.Fatalf("got %v, want %v", pgm.data, want)
+	}
+}
+
+func TestPPMWarpPerspectiveIdentity(t *testing.T) {
+	ppm := newTestPPM(3, 3, func(x, y int) Pixel { return Pixel{uint16(x), uint16(y), 5} })
+	if err := ppm.WarpPerspective(IdentityAffine().Homography(), WarpOptions{Interpolation: Bicubic}, Pixel{}); err != nil {
+		t.Fatal(err)
+	}
+	want := newTestPPM(3, 3, func(x, y int) Pixel { return Pixel{uint16(x), uint16(y), 5} })
+	if !reflect.DeepEqual(ppm.data, want.data) {
+		t.Fatalf("got %v", ppm.data)
+	}
+	if err := ppm.WarpPerspective(Homography{}, WarpOptions{}, Pixel{}); err == nil {
+		t.Error("singular homography accepted")
+	}
+}